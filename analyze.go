@@ -0,0 +1,184 @@
+package predicate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"strings"
+
+	"github.com/gravitational/trace"
+	"github.com/vulcand/predicate/internal/astutil"
+)
+
+// Deps lists identifiers, properties, functions and methods referenced by
+// an expression, in the order they appear.
+type Deps struct {
+	// Identifiers are selectors like user.traits.logins.
+	Identifiers []IdentifierRef
+	// Properties are map keys used with literal indexes,
+	// e.g. resource.metadata.labels["env"].
+	Properties []PropertyRef
+	// Functions are called functions, including module
+	// functions like number.DivisibleBy.
+	Functions []CallRef
+	// Methods are called methods.
+	Methods []CallRef
+}
+
+// IdentifierRef is a reference to an identifier.
+type IdentifierRef struct {
+	// Selector is the identifier path, e.g. ["user", "traits", "logins"].
+	Selector []string
+	// Span is the location of the identifier.
+	Span Span
+}
+
+// PropertyRef is a reference to a property with a literal key.
+type PropertyRef struct {
+	// Selector is the path of the indexed identifier,
+	// empty if a property of another expression is used.
+	Selector []string
+	// Key is the literal key, e.g. "env".
+	Key any
+	// Span is the location of the index expression.
+	Span Span
+}
+
+// CallRef is a reference to a function or a method.
+type CallRef struct {
+	// Name is the name used in Def.Functions or Def.Methods.
+	Name string
+	// Span is the location of the call expression.
+	Span Span
+}
+
+// IdentifierPaths returns unique identifier paths like user.traits.logins.
+func (d Deps) IdentifierPaths() []string {
+	var out []string
+	seen := make(map[string]struct{})
+	for _, id := range d.Identifiers {
+		path := strings.Join(id.Selector, ".")
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+		out = append(out, path)
+	}
+	return out
+}
+
+// Analyze returns identifiers, properties, functions and methods referenced
+// by the expression without calling any of them. Calls are told apart the
+// same way Parse does, so x.f() is a method call if f is in Methods and
+// a call of the module function x.f otherwise.
+func (d Def) Analyze(in string) (Deps, error) {
+	expr, err := parser.ParseExpr(in)
+	if err != nil {
+		return Deps{}, err
+	}
+	a := &analyzer{d: d, in: in}
+	if err := a.walk(expr); err != nil {
+		return Deps{}, trace.Wrap(err)
+	}
+	return a.deps, nil
+}
+
+type analyzer struct {
+	d    Def
+	in   string
+	deps Deps
+}
+
+func (a *analyzer) walk(expr ast.Expr) error {
+	switch n := expr.(type) {
+	case *ast.BinaryExpr:
+		if err := a.walk(n.X); err != nil {
+			return trace.Wrap(err)
+		}
+		return a.walk(n.Y)
+
+	case *ast.ParenExpr:
+		return a.walk(n.X)
+
+	case *ast.UnaryExpr:
+		return a.walk(n.X)
+
+	case *ast.BasicLit:
+		return nil
+
+	case *ast.IndexExpr:
+		if err := a.walk(n.X); err != nil {
+			return trace.Wrap(err)
+		}
+		if lit, ok := astutil.Unparen(n.Index).(*ast.BasicLit); ok {
+			key, err := literalToValue(lit)
+			if err != nil {
+				return trace.Wrap(err)
+			}
+			selector, _ := astutil.SelectorOf(n.X)
+			a.deps.Properties = append(a.deps.Properties, PropertyRef{
+				Selector: selector,
+				Key:      key,
+				Span:     spanOf(a.in, n),
+			})
+		}
+		return a.walk(n.Index)
+
+	case *ast.SelectorExpr:
+		fields, err := evaluateSelector(n, []string{})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		a.addIdentifier(fields, n)
+		return nil
+
+	case *ast.Ident:
+		a.addIdentifier([]string{n.Name}, n)
+		return nil
+
+	case *ast.CallExpr:
+		return a.walkCall(n)
+
+	default:
+		return trace.BadParameter("%T is not supported", expr)
+	}
+}
+
+func (a *analyzer) addIdentifier(selector []string, node ast.Node) {
+	a.deps.Identifiers = append(a.deps.Identifiers, IdentifierRef{
+		Selector: selector,
+		Span:     spanOf(a.in, node),
+	})
+}
+
+func (a *analyzer) walkCall(call *ast.CallExpr) error {
+	ref := CallRef{Span: spanOf(a.in, call)}
+	switch f := call.Fun.(type) {
+	case *ast.Ident:
+		ref.Name = f.Name
+		a.deps.Functions = append(a.deps.Functions, ref)
+	case *ast.SelectorExpr:
+		if _, ok := a.d.Methods[f.Sel.Name]; ok {
+			ref.Name = f.Sel.Name
+			a.deps.Methods = append(a.deps.Methods, ref)
+			if err := a.walk(f.X); err != nil {
+				return trace.Wrap(err)
+			}
+			break
+		}
+		id, ok := f.X.(*ast.Ident)
+		if !ok {
+			return trace.BadParameter("expected selector identifier, got: %T", f.X)
+		}
+		ref.Name = fmt.Sprintf("%s.%s", id.Name, f.Sel.Name)
+		a.deps.Functions = append(a.deps.Functions, ref)
+	default:
+		return trace.BadParameter("unknown function type %T", f)
+	}
+	for _, arg := range call.Args {
+		if err := a.walk(arg); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package predicate
+
+import (
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyze(t *testing.T) {
+	t.Parallel()
+
+	called := false
+	d := Def{
+		Functions: map[string]any{
+			"contains": func(a, b any) bool {
+				called = true
+				return false
+			},
+		},
+		Methods: map[string]any{
+			"hasPrefix": hasPrefix,
+		},
+		GetIdentifier: func(selector []string) (any, error) {
+			called = true
+			return nil, nil
+		},
+	}
+
+	in := `contains(user.traits.logins, "root") &&
+	resource.metadata.labels["env"] == "prod" &&
+	user.name.hasPrefix(prefix) && number.DivisibleBy(2)`
+	deps, err := d.Analyze(in)
+	require.NoError(t, err)
+	require.False(t, called, "Analyze must not call functions or identifiers")
+
+	require.Equal(t, []string{
+		"user.traits.logins",
+		"resource.metadata.labels",
+		"user.name",
+		"prefix",
+	}, deps.IdentifierPaths())
+
+	require.Equal(t, []IdentifierRef{
+		{Selector: []string{"user", "traits", "logins"}, Span: Span{
+			Start: Position{Offset: 9, Line: 1, Column: 10},
+			End:   Position{Offset: 27, Line: 1, Column: 28},
+		}},
+		{Selector: []string{"resource", "metadata", "labels"}, Span: Span{
+			Start: Position{Offset: 41, Line: 2, Column: 2},
+			End:   Position{Offset: 65, Line: 2, Column: 26},
+		}},
+		{Selector: []string{"user", "name"}, Span: Span{
+			Start: Position{Offset: 87, Line: 3, Column: 2},
+			End:   Position{Offset: 96, Line: 3, Column: 11},
+		}},
+		{Selector: []string{"prefix"}, Span: Span{
+			Start: Position{Offset: 107, Line: 3, Column: 22},
+			End:   Position{Offset: 113, Line: 3, Column: 28},
+		}},
+	}, deps.Identifiers)
+
+	require.Len(t, deps.Properties, 1)
+	require.Equal(t, []string{"resource", "metadata", "labels"}, deps.Properties[0].Selector)
+	require.Equal(t, "env", deps.Properties[0].Key)
+	require.Equal(t, `resource.metadata.labels["env"]`, in[deps.Properties[0].Span.Start.Offset:deps.Properties[0].Span.End.Offset])
+
+	var functions, methods []string
+	for _, f := range deps.Functions {
+		functions = append(functions, f.Name)
+	}
+	for _, m := range deps.Methods {
+		methods = append(methods, m.Name)
+		require.Equal(t, `user.name.hasPrefix(prefix)`, in[m.Span.Start.Offset:m.Span.End.Offset])
+	}
+	require.Equal(t, []string{"contains", "number.DivisibleBy"}, functions)
+	require.Equal(t, []string{"hasPrefix"}, methods)
+}
+
+func TestAnalyzeErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, in := range []string{
+		")(",
+		"func(){}()",
+		`f(1)(2)`,
+		`a[1].b`,
+		`"a".b()`,
+	} {
+		_, err := Def{}.Analyze(in)
+		require.Error(t, err, in)
+	}
+
+	_, err := Def{}.Analyze(`x[0x]`)
+	require.Error(t, err)
+	_, err = Def{}.Analyze(`a[1.5e1000]`)
+	require.True(t, trace.IsBadParameter(err), "unexpected error %v", err)
+}
@@ -16,12 +16,18 @@ limitations under the License.
 */
 
 // Package builder is used to construct predicate
-// expressions using builder functions.
+// expressions using builder functions. It has nodes for every
+// operator the parser supports, the language has no arithmetic.
 package builder
 
 import (
 	"fmt"
+	"go/token"
+	"math"
+	"strconv"
 	"strings"
+
+	"github.com/gravitational/trace"
 )
 
 // Expr is an expression builder,
@@ -32,6 +38,38 @@ type Expr interface {
 	String() string
 }
 
+// precedenceExpr is implemented by expressions with operators,
+// other expressions never need parentheses.
+type precedenceExpr interface {
+	precedence() int
+}
+
+// precedence returns the precedence of the outermost operator of
+// the expression, e.g. token.LOR.Precedence() for ||.
+func precedence(e Expr) int {
+	if p, ok := e.(precedenceExpr); ok {
+		return p.precedence()
+	}
+	return token.UnaryPrec + 1
+}
+
+// operand returns the text of the operand, in parentheses if its operator
+// binds looser than minPrec, so parsing the text gives back the same tree.
+func operand(e Expr, minPrec int) string {
+	if precedence(e) < minPrec {
+		return fmt.Sprintf("(%v)", e)
+	}
+	return e.String()
+}
+
+// binary returns the text of the left associative binary operator.
+// Right operands with the same precedence need parentheses,
+// e.g. a && (b && c).
+func binary(op token.Token, left, right Expr) string {
+	prec := op.Precedence()
+	return fmt.Sprintf("%v %v %v", operand(left, prec), op, operand(right, prec+1))
+}
+
 // IdentifierExpr is identifier expression.
 type IdentifierExpr string
 
@@ -57,6 +95,55 @@ func (s StringExpr) String() string {
 	return fmt.Sprintf("%q", string(s))
 }
 
+// Int returns integer expression, see IntE.
+func Int(v int) IntExpr {
+	return IntExpr(v)
+}
+
+// IntE returns integer expression. The language has no negative
+// literals, so negative values are rejected.
+func IntE(v int) (IntExpr, error) {
+	if v < 0 {
+		return 0, trace.BadParameter("negative integer %v can not be written as a literal", v)
+	}
+	return IntExpr(v), nil
+}
+
+// IntExpr is an integer expression. The language has no negative
+// literals, so negative values do not parse.
+type IntExpr int
+
+func (i IntExpr) String() string {
+	return strconv.Itoa(int(i))
+}
+
+// Float returns floating point expression, see FloatE.
+func Float(v float64) FloatExpr {
+	return FloatExpr(v)
+}
+
+// FloatE returns floating point expression. The language has no negative,
+// infinite or NaN literals, so these values are rejected.
+func FloatE(v float64) (FloatExpr, error) {
+	if v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, trace.BadParameter("%v can not be written as a literal", v)
+	}
+	return FloatExpr(v), nil
+}
+
+// FloatExpr is a floating point expression. The language has no negative,
+// infinite or NaN literals, so these values do not parse.
+type FloatExpr float64
+
+func (f FloatExpr) String() string {
+	s := strconv.FormatFloat(float64(f), 'g', -1, 64)
+	if math.Trunc(float64(f)) == float64(f) && !strings.ContainsAny(s, "e") {
+		// Keep the literal a float, e.g. 4.0 and not 4.
+		s += ".0"
+	}
+	return s
+}
+
 // StringsExpr is a slice of strings.
 type StringsExpr []string
 
@@ -103,7 +190,11 @@ type NotExpr struct {
 
 // String returns function call expression used in rules.
 func (n NotExpr) String() string {
-	return fmt.Sprintf("!%v", n.Expr)
+	return "!" + operand(n.Expr, token.UnaryPrec)
+}
+
+func (n NotExpr) precedence() int {
+	return token.UnaryPrec
 }
 
 // Contains returns contains function call expression.
@@ -144,7 +235,11 @@ type AndExpr struct {
 
 // String returns expression text used in rules.
 func (a AndExpr) String() string {
-	return fmt.Sprintf("%v && %v", a.Left, a.Right)
+	return binary(token.LAND, a.Left, a.Right)
+}
+
+func (a AndExpr) precedence() int {
+	return token.LAND.Precedence()
 }
 
 // Or returns || expression.
@@ -165,5 +260,184 @@ type OrExpr struct {
 
 // String returns expression text used in rules.
 func (a OrExpr) String() string {
-	return fmt.Sprintf("%v || %v", a.Left, a.Right)
+	return binary(token.LOR, a.Left, a.Right)
+}
+
+func (a OrExpr) precedence() int {
+	return token.LOR.Precedence()
+}
+
+// Index returns index expression.
+func Index(x, key Expr) IndexExpr {
+	return IndexExpr{X: x, Key: key}
+}
+
+// IndexExpr returns the property of a map,
+// e.g. labels["env"] where X is labels and Key is "env".
+type IndexExpr struct {
+	// X is the indexed expression
+	X Expr
+	// Key is the property key
+	Key Expr
+}
+
+// String returns expression text used in rules.
+func (i IndexExpr) String() string {
+	return fmt.Sprintf("%v[%v]", operand(i.X, token.UnaryPrec+1), i.Key)
+}
+
+// EQ returns == expression.
+func EQ(left, right Expr) EQExpr {
+	return EQExpr{
+		Left:  left,
+		Right: right,
+	}
+}
+
+// EQExpr returns == expression that checks if
+// the left value is equal to the right one.
+type EQExpr struct {
+	// Left is a left argument of == operator expression
+	Left Expr
+	// Right is a right argument of == operator expression
+	Right Expr
+}
+
+// String returns expression text used in rules.
+func (e EQExpr) String() string {
+	return binary(token.EQL, e.Left, e.Right)
+}
+
+func (e EQExpr) precedence() int {
+	return token.EQL.Precedence()
+}
+
+// NEQ returns != expression.
+func NEQ(left, right Expr) NEQExpr {
+	return NEQExpr{
+		Left:  left,
+		Right: right,
+	}
+}
+
+// NEQExpr returns != expression that checks if
+// the left value is not equal to the right one.
+type NEQExpr struct {
+	// Left is a left argument of != operator expression
+	Left Expr
+	// Right is a right argument of != operator expression
+	Right Expr
+}
+
+// String returns expression text used in rules.
+func (e NEQExpr) String() string {
+	return binary(token.NEQ, e.Left, e.Right)
+}
+
+func (e NEQExpr) precedence() int {
+	return token.NEQ.Precedence()
+}
+
+// LT returns < expression.
+func LT(left, right Expr) LTExpr {
+	return LTExpr{
+		Left:  left,
+		Right: right,
+	}
+}
+
+// LTExpr returns < expression that checks if
+// the left value is less than the right one.
+type LTExpr struct {
+	// Left is a left argument of < operator expression
+	Left Expr
+	// Right is a right argument of < operator expression
+	Right Expr
+}
+
+// String returns expression text used in rules.
+func (e LTExpr) String() string {
+	return binary(token.LSS, e.Left, e.Right)
+}
+
+func (e LTExpr) precedence() int {
+	return token.LSS.Precedence()
+}
+
+// LE returns <= expression.
+func LE(left, right Expr) LEExpr {
+	return LEExpr{
+		Left:  left,
+		Right: right,
+	}
+}
+
+// LEExpr returns <= expression that checks if
+// the left value is less than or equal to the right one.
+type LEExpr struct {
+	// Left is a left argument of <= operator expression
+	Left Expr
+	// Right is a right argument of <= operator expression
+	Right Expr
+}
+
+// String returns expression text used in rules.
+func (e LEExpr) String() string {
+	return binary(token.LEQ, e.Left, e.Right)
+}
+
+func (e LEExpr) precedence() int {
+	return token.LEQ.Precedence()
+}
+
+// GT returns > expression.
+func GT(left, right Expr) GTExpr {
+	return GTExpr{
+		Left:  left,
+		Right: right,
+	}
+}
+
+// GTExpr returns > expression that checks if
+// the left value is greater than the right one.
+type GTExpr struct {
+	// Left is a left argument of > operator expression
+	Left Expr
+	// Right is a right argument of > operator expression
+	Right Expr
+}
+
+// String returns expression text used in rules.
+func (e GTExpr) String() string {
+	return binary(token.GTR, e.Left, e.Right)
+}
+
+func (e GTExpr) precedence() int {
+	return token.GTR.Precedence()
+}
+
+// GE returns >= expression.
+func GE(left, right Expr) GEExpr {
+	return GEExpr{
+		Left:  left,
+		Right: right,
+	}
+}
+
+// GEExpr returns >= expression that checks if
+// the left value is greater than or equal to the right one.
+type GEExpr struct {
+	// Left is a left argument of >= operator expression
+	Left Expr
+	// Right is a right argument of >= operator expression
+	Right Expr
+}
+
+// String returns expression text used in rules.
+func (e GEExpr) String() string {
+	return binary(token.GEQ, e.Left, e.Right)
+}
+
+func (e GEExpr) precedence() int {
+	return token.GEQ.Precedence()
 }
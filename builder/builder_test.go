@@ -0,0 +1,218 @@
+package builder
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestString(t *testing.T) {
+	t.Parallel()
+
+	a, b, c := Identifier("a"), Identifier("b"), Identifier("c")
+	for _, tc := range []struct {
+		expr   Expr
+		expect string
+	}{
+		{expr: And(a, b), expect: `a && b`},
+		{expr: And(Or(a, b), c), expect: `(a || b) && c`},
+		{expr: Or(a, And(b, c)), expect: `a || b && c`},
+		{expr: And(a, And(b, c)), expect: `a && (b && c)`},
+		{expr: And(And(a, b), c), expect: `a && b && c`},
+		{expr: Not(And(a, b)), expect: `!(a && b)`},
+		{expr: Not(Not(a)), expect: `!!a`},
+		{expr: And(Not(a), Equals(Identifier("user.name"), String("bob"))), expect: `!a && equals(user.name, "bob")`},
+		{expr: Not(Contains(Identifier("user.roles"), String("admin"))), expect: `!contains(user.roles, "admin")`},
+		{expr: And(GE(a, Int(1)), LT(a, Float(2.5))), expect: `a >= 1 && a < 2.5`},
+		{expr: Or(NEQ(a, Float(4)), LE(b, Float(1e21))), expect: `a != 4.0 || b <= 1e+21`},
+		{expr: EQ(GT(a, b), Identifier("true")), expect: `a > b == true`},
+		{expr: EQ(a, EQ(b, c)), expect: `a == (b == c)`},
+		{expr: Not(EQ(Index(Identifier("labels"), String("env")), String("prod"))), expect: `!(labels["env"] == "prod")`},
+		{expr: Index(Index(a, String("x")), Int(0)), expect: `a["x"][0]`},
+		{expr: Index(And(a, b), String("x")), expect: `(a && b)["x"]`},
+	} {
+		t.Run(tc.expect, func(t *testing.T) {
+			require.Equal(t, tc.expect, tc.expr.String())
+		})
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	t.Parallel()
+
+	i, err := IntE(3)
+	require.NoError(t, err)
+	require.Equal(t, Int(3), i)
+	f, err := FloatE(0.5)
+	require.NoError(t, err)
+	require.Equal(t, Float(0.5), f)
+
+	// Numbers without literals are rejected.
+	_, err = IntE(-1)
+	require.True(t, trace.IsBadParameter(err), "unexpected error %v", err)
+	for _, f := range []float64{-0.5, math.Inf(1), math.Inf(-1), math.NaN()} {
+		_, err := FloatE(f)
+		require.True(t, trace.IsBadParameter(err), "%v: unexpected error %v", f, err)
+	}
+}
+
+// TestParseString checks that parsing the text of random expressions
+// gives back the same expressions.
+func TestParseString(t *testing.T) {
+	t.Parallel()
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		expr := randomExpr(rnd, 4)
+		in := expr.String()
+		parsed, err := parser.ParseExpr(in)
+		require.NoError(t, err, in)
+		out, err := fromAST(parsed)
+		require.NoError(t, err, in)
+		require.Equal(t, expr, out, in)
+	}
+}
+
+func randomExpr(rnd *rand.Rand, depth int) Expr {
+	if depth == 0 {
+		return Identifier(fmt.Sprintf("x%d", rnd.Intn(3)))
+	}
+	switch rnd.Intn(9) {
+	case 0:
+		return And(randomExpr(rnd, depth-1), randomExpr(rnd, depth-1))
+	case 1:
+		return Or(randomExpr(rnd, depth-1), randomExpr(rnd, depth-1))
+	case 2:
+		return Not(randomExpr(rnd, depth-1))
+	case 3:
+		return Equals(Identifier("user.name"), String(fmt.Sprintf("n%d", rnd.Intn(3))))
+	case 4:
+		return Contains(Identifier("user.roles"), String("admin"))
+	case 5:
+		return randomComparison(rnd, randomExpr(rnd, depth-1), randomExpr(rnd, depth-1))
+	case 6:
+		return randomComparison(rnd, Index(Identifier("labels"), String("env")), Float(float64(rnd.Intn(20))/4))
+	case 7:
+		return randomComparison(rnd, Identifier("user.age"), Int(rnd.Intn(100)))
+	default:
+		return Identifier(fmt.Sprintf("x%d", rnd.Intn(3)))
+	}
+}
+
+func randomComparison(rnd *rand.Rand, left, right Expr) Expr {
+	return []func(left, right Expr) Expr{
+		func(l, r Expr) Expr { return EQ(l, r) },
+		func(l, r Expr) Expr { return NEQ(l, r) },
+		func(l, r Expr) Expr { return LT(l, r) },
+		func(l, r Expr) Expr { return LE(l, r) },
+		func(l, r Expr) Expr { return GT(l, r) },
+		func(l, r Expr) Expr { return GE(l, r) },
+	}[rnd.Intn(6)](left, right)
+}
+
+// fromAST converts parsed expressions back to builder expressions.
+func fromAST(expr ast.Expr) (Expr, error) {
+	switch n := expr.(type) {
+	case *ast.ParenExpr:
+		return fromAST(n.X)
+	case *ast.Ident:
+		return Identifier(n.Name), nil
+	case *ast.SelectorExpr:
+		x, err := fromAST(n.X)
+		if err != nil {
+			return nil, err
+		}
+		return Identifier(fmt.Sprintf("%v.%v", x, n.Sel.Name)), nil
+	case *ast.BasicLit:
+		switch n.Kind {
+		case token.STRING:
+			s, err := strconv.Unquote(n.Value)
+			return String(s), err
+		case token.INT:
+			i, err := strconv.Atoi(n.Value)
+			if err != nil {
+				return nil, err
+			}
+			return Int(i), nil
+		case token.FLOAT:
+			f, err := strconv.ParseFloat(n.Value, 64)
+			if err != nil {
+				return nil, err
+			}
+			return Float(f), nil
+		}
+		return nil, fmt.Errorf("unexpected literal %v", n.Value)
+	case *ast.IndexExpr:
+		x, err := fromAST(n.X)
+		if err != nil {
+			return nil, err
+		}
+		key, err := fromAST(n.Index)
+		if err != nil {
+			return nil, err
+		}
+		return Index(x, key), nil
+	case *ast.UnaryExpr:
+		x, err := fromAST(n.X)
+		if err != nil || n.Op != token.NOT {
+			return nil, fmt.Errorf("unexpected operator %v %v", n.Op, err)
+		}
+		return Not(x), nil
+	case *ast.BinaryExpr:
+		x, err := fromAST(n.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := fromAST(n.Y)
+		if err != nil {
+			return nil, err
+		}
+		switch n.Op {
+		case token.LAND:
+			return And(x, y), nil
+		case token.LOR:
+			return Or(x, y), nil
+		case token.EQL:
+			return EQ(x, y), nil
+		case token.NEQ:
+			return NEQ(x, y), nil
+		case token.LSS:
+			return LT(x, y), nil
+		case token.LEQ:
+			return LE(x, y), nil
+		case token.GTR:
+			return GT(x, y), nil
+		case token.GEQ:
+			return GE(x, y), nil
+		}
+		return nil, fmt.Errorf("unexpected operator %v", n.Op)
+	case *ast.CallExpr:
+		if len(n.Args) != 2 {
+			return nil, fmt.Errorf("unexpected call with %v arguments", len(n.Args))
+		}
+		x, err := fromAST(n.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		y, err := fromAST(n.Args[1])
+		if err != nil {
+			return nil, err
+		}
+		switch fmt.Sprint(n.Fun) {
+		case "equals":
+			return Equals(x, y), nil
+		case "contains":
+			return Contains(x, y), nil
+		}
+		return nil, fmt.Errorf("unexpected function %v", n.Fun)
+	}
+	return nil, fmt.Errorf("unexpected expression %T", expr)
+}
@@ -0,0 +1,266 @@
+package predicate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+
+	"github.com/gravitational/trace"
+	"github.com/vulcand/predicate/internal/astutil"
+)
+
+const (
+	// DefaultMaxLength is the length of strings and lists assumed
+	// for the worst-case cost when it is not known statically.
+	DefaultMaxLength = 1024
+	// DefaultExpectedLength is the length of strings and lists assumed
+	// for the expected cost when it is not known statically.
+	DefaultExpectedLength = 16
+)
+
+// Cost describes the cost of calling a function, a method or an operator.
+// Calls without a cost, e.g. with a nil FunctionInfo.Cost, cost 1, and the
+// zero value is free.
+type Cost struct {
+	// Base is the cost of every call.
+	Base float64
+	// PerUnit is the cost per byte of the string, or per element of the
+	// list, passed as the argument at index Arg.
+	PerUnit float64
+	// Arg is the index of the argument the cost is linear in,
+	// method receivers are at index 0.
+	Arg int
+	// Estimate optionally replaces Base and PerUnit for other cost shapes,
+	// e.g. quadratic ones. It is called with the lengths of all arguments.
+	Estimate func(lengths []int) float64
+}
+
+// Constant returns the cost of calls that do not depend on arguments.
+func Constant(cost float64) *Cost {
+	return &Cost{Base: cost}
+}
+
+// Linear returns the cost of calls linear in the length of the argument
+// at index arg.
+func Linear(base, perUnit float64, arg int) *Cost {
+	return &Cost{Base: base, PerUnit: perUnit, Arg: arg}
+}
+
+// linearSum returns the cost of calls linear in the total length
+// of the arguments at the given indexes.
+func linearSum(base, perUnit float64, args ...int) *Cost {
+	return &Cost{Estimate: func(lengths []int) float64 {
+		cost := base
+		for _, arg := range args {
+			if arg < len(lengths) {
+				cost += perUnit * float64(lengths[arg])
+			}
+		}
+		return cost
+	}}
+}
+
+// estimate returns the cost of a call with arguments of the given lengths,
+// 1 if the cost is not set.
+func (c *Cost) estimate(lengths []int) float64 {
+	switch {
+	case c == nil:
+		return 1
+	case c.Estimate != nil:
+		return c.Estimate(lengths)
+	}
+	cost := c.Base
+	if c.Arg >= 0 && c.Arg < len(lengths) {
+		cost += c.PerUnit * float64(lengths[c.Arg])
+	}
+	return cost
+}
+
+// CostOptions configures cost estimation.
+type CostOptions struct {
+	// MaxLength is the length of strings and lists that are not literals
+	// assumed for the worst-case cost. Defaults to DefaultMaxLength.
+	MaxLength int
+	// ExpectedLength is the length of strings and lists that are not
+	// literals assumed for the expected cost. Defaults to
+	// DefaultExpectedLength.
+	ExpectedLength int
+}
+
+// CheckAndSetDefaults checks and sets default values.
+func (o *CostOptions) CheckAndSetDefaults() error {
+	if o.MaxLength < 0 || o.ExpectedLength < 0 {
+		return trace.BadParameter("lengths can not be negative")
+	}
+	if o.MaxLength == 0 {
+		o.MaxLength = DefaultMaxLength
+	}
+	if o.ExpectedLength == 0 {
+		o.ExpectedLength = DefaultExpectedLength
+	}
+	if o.ExpectedLength > o.MaxLength {
+		return trace.BadParameter("expected length %v is over max length %v", o.ExpectedLength, o.MaxLength)
+	}
+	return nil
+}
+
+// CostEstimate is the estimated cost of evaluating an expression.
+type CostEstimate struct {
+	// Worst is the cost when every function is called with arguments
+	// of the maximum length.
+	Worst float64
+	// Expected is the cost when arguments have the expected length.
+	Expected float64
+}
+
+// String returns the estimate in worst/expected format.
+func (c CostEstimate) String() string {
+	return fmt.Sprintf("worst %g, expected %g", c.Worst, c.Expected)
+}
+
+// EstimateCost returns the cost of evaluating the expression without
+// evaluating it. Costs of functions, methods and operators are taken from
+// FunctionInfo, keyed by name or by operator, e.g. "==", and default to 1.
+// Identifier and property lookups cost 1, literals are free. Both operands
+// of && and || are counted, as they are always evaluated.
+func (d Def) EstimateCost(in string, opts CostOptions) (CostEstimate, error) {
+	expr, err := parser.ParseExpr(in)
+	if err != nil {
+		return CostEstimate{}, err
+	}
+	est, err := d.EstimateCostExpr(expr, opts)
+	return est, trace.Wrap(err)
+}
+
+// EstimateCostExpr returns the cost of evaluating the parsed expression,
+// see EstimateCost.
+func (d Def) EstimateCostExpr(expr ast.Expr, opts CostOptions) (CostEstimate, error) {
+	if err := opts.CheckAndSetDefaults(); err != nil {
+		return CostEstimate{}, trace.Wrap(err)
+	}
+	e := &estimator{d: d, opts: opts}
+	est, err := e.cost(expr)
+	return est, trace.Wrap(err)
+}
+
+// checkCost fails with trace.LimitExceeded if the worst-case cost of
+// the expression is over Def.MaxCost.
+func (d Def) checkCost(expr ast.Expr) error {
+	if d.MaxCost <= 0 {
+		return nil
+	}
+	est, err := d.EstimateCostExpr(expr, d.CostOptions)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if est.Worst > d.MaxCost {
+		return trace.LimitExceeded("expression cost %g is over the limit of %g", est.Worst, d.MaxCost)
+	}
+	return nil
+}
+
+type estimator struct {
+	d    Def
+	opts CostOptions
+}
+
+func (e *estimator) cost(expr ast.Expr) (CostEstimate, error) {
+	switch n := expr.(type) {
+	case *ast.ParenExpr:
+		return e.cost(n.X)
+
+	case *ast.BasicLit:
+		return CostEstimate{}, nil
+
+	case *ast.Ident, *ast.SelectorExpr:
+		return CostEstimate{Worst: 1, Expected: 1}, nil
+
+	case *ast.IndexExpr:
+		return e.sum(1, n.X, n.Index)
+
+	case *ast.UnaryExpr:
+		return e.call(n.Op.String(), []ast.Expr{n.X})
+
+	case *ast.BinaryExpr:
+		return e.call(n.Op.String(), []ast.Expr{n.X, n.Y})
+
+	case *ast.CallExpr:
+		name, args, err := e.function(n)
+		if err != nil {
+			return CostEstimate{}, trace.Wrap(err)
+		}
+		return e.call(name, args)
+
+	default:
+		return CostEstimate{}, trace.BadParameter("%T is not supported", expr)
+	}
+}
+
+// call returns the cost of calling the named function with the arguments,
+// including the cost of evaluating them.
+func (e *estimator) call(name string, args []ast.Expr) (CostEstimate, error) {
+	cost := e.d.FunctionInfo[name].Cost
+	est, err := e.sum(0, args...)
+	if err != nil {
+		return CostEstimate{}, trace.Wrap(err)
+	}
+	est.Worst += cost.estimate(e.lengths(args, e.opts.MaxLength))
+	est.Expected += cost.estimate(e.lengths(args, e.opts.ExpectedLength))
+	return est, nil
+}
+
+// sum returns the base cost plus the cost of evaluating the expressions.
+func (e *estimator) sum(base float64, exprs ...ast.Expr) (CostEstimate, error) {
+	est := CostEstimate{Worst: base, Expected: base}
+	for _, expr := range exprs {
+		c, err := e.cost(expr)
+		if err != nil {
+			return CostEstimate{}, trace.Wrap(err)
+		}
+		est.Worst += c.Worst
+		est.Expected += c.Expected
+	}
+	return est, nil
+}
+
+// lengths returns lengths of the arguments, the length of string literals
+// is known, other values are assumed to have the given length.
+func (e *estimator) lengths(args []ast.Expr, assumed int) []int {
+	out := make([]int, len(args))
+	for i, arg := range args {
+		out[i] = assumed
+		lit, ok := astutil.Unparen(arg).(*ast.BasicLit)
+		if !ok {
+			continue
+		}
+		out[i] = 1
+		if lit.Kind == token.STRING {
+			if s, err := strconv.Unquote(lit.Value); err == nil {
+				out[i] = len(s)
+			}
+		}
+	}
+	return out
+}
+
+// function returns the name the call is defined by, and its arguments with
+// the method receiver first, resolved the same way Parse does.
+func (e *estimator) function(call *ast.CallExpr) (string, []ast.Expr, error) {
+	switch f := call.Fun.(type) {
+	case *ast.Ident:
+		return f.Name, call.Args, nil
+	case *ast.SelectorExpr:
+		if _, ok := e.d.Methods[f.Sel.Name]; ok {
+			return f.Sel.Name, append([]ast.Expr{f.X}, call.Args...), nil
+		}
+		id, ok := f.X.(*ast.Ident)
+		if !ok {
+			return "", nil, trace.BadParameter("expected selector identifier, got: %T", f.X)
+		}
+		return fmt.Sprintf("%s.%s", id.Name, f.Sel.Name), call.Args, nil
+	default:
+		return "", nil, trace.BadParameter("unknown function type %T", f)
+	}
+}
@@ -0,0 +1,131 @@
+package predicate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateCost(t *testing.T) {
+	t.Parallel()
+
+	d := Def{
+		Methods: map[string]any{
+			"lower": strings.ToLower,
+		},
+		FunctionInfo: map[string]FunctionInfo{
+			"matches": {Cost: Linear(2, 1, 1)},
+			"lower":   {Cost: Linear(0, 1, 0)},
+			"pairs": {Cost: &Cost{Estimate: func(lengths []int) float64 {
+				return float64(lengths[0] * lengths[1])
+			}}},
+			"free": {Cost: Constant(0.5)},
+			"zero": {Cost: Constant(0)},
+		},
+	}
+	opts := CostOptions{MaxLength: 100, ExpectedLength: 10}
+
+	tests := []struct {
+		in   string
+		want CostEstimate
+	}{
+		{in: `"x"`, want: CostEstimate{}},
+		{in: `a`, want: CostEstimate{Worst: 1, Expected: 1}},
+		{in: `a == "b"`, want: CostEstimate{Worst: 2, Expected: 2}},
+		{in: `!a`, want: CostEstimate{Worst: 2, Expected: 2}},
+		{in: `m["k"]`, want: CostEstimate{Worst: 2, Expected: 2}},
+		{in: `a && b`, want: CostEstimate{Worst: 3, Expected: 3}},
+		{in: `(a || b) && c`, want: CostEstimate{Worst: 5, Expected: 5}},
+		{in: `matches("abc", x)`, want: CostEstimate{Worst: 103, Expected: 13}},
+		{in: `matches(x, "abcd")`, want: CostEstimate{Worst: 7, Expected: 7}},
+		{in: `x.lower() == "a"`, want: CostEstimate{Worst: 102, Expected: 12}},
+		{in: `pairs(a, b)`, want: CostEstimate{Worst: 10002, Expected: 102}},
+		{in: `free() || free()`, want: CostEstimate{Worst: 2, Expected: 2}},
+		{in: `zero(zero())`, want: CostEstimate{}},
+		{in: `strings.unknown(a)`, want: CostEstimate{Worst: 2, Expected: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			est, err := d.EstimateCost(tt.in, opts)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, est)
+		})
+	}
+
+	est, err := d.EstimateCost(`matches("abc", x)`, CostOptions{})
+	require.NoError(t, err)
+	require.Equal(t, CostEstimate{Worst: 2 + DefaultMaxLength + 1, Expected: 2 + DefaultExpectedLength + 1}, est)
+	require.Equal(t, "worst 1027, expected 19", est.String())
+
+	_, err = d.EstimateCost(`a`, CostOptions{MaxLength: 10, ExpectedLength: 20})
+	require.True(t, trace.IsBadParameter(err))
+}
+
+func TestModuleCosts(t *testing.T) {
+	t.Parallel()
+
+	regex, err := RegexModule(RegexOptions{})
+	require.NoError(t, err)
+	glob, err := GlobModule(GlobOptions{})
+	require.NoError(t, err)
+	d, err := Def{}.Merge(regex, glob, SetsModule(), StringsModule(), NetworkModule(), JSONModule())
+	require.NoError(t, err)
+	opts := CostOptions{MaxLength: 100, ExpectedLength: 10}
+
+	for _, tc := range []struct {
+		input  string
+		expect float64
+	}{
+		{input: `matches(name, "^a+$")`, expect: 1 + 1 + 100 + 4},
+		{input: `name.matches(pattern)`, expect: 2 + 1 + 200},
+		{input: `regexReplace(name, "a", "b")`, expect: 1 + 1 + 100 + 1 + 1},
+		{input: `glob(name, "a*")`, expect: 1 + 1 + 100 + 2},
+		{input: `intersection(a, b)`, expect: 2 + 1 + 200},
+		{input: `containsAll(a, b)`, expect: 2 + 1 + 200},
+		{input: `join(logins, ",")`, expect: 1 + 1 + 100},
+		{input: `split(name, ",")`, expect: 1 + 1 + 100},
+		{input: `len(name)`, expect: 1 + 1},
+		{input: `inAnyCIDR(ip, "10.0.0.0/8", "192.168.0.0/16")`, expect: 1 + 1 + 2},
+		{input: `inAnyCIDR(ip, cidrs)`, expect: 2 + 1 + 100},
+		{input: `cidrContains("10.0.0.0/8", ip)`, expect: 1 + 1},
+		{input: `jsonPointer(doc, "/a/b")`, expect: 1 + 1 + 4},
+		{input: `jsonPath(doc, "$.a")`, expect: 1 + 1 + 100 + 3},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			est, err := d.EstimateCost(tc.input, opts)
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, est.Worst)
+		})
+	}
+}
+
+func TestParseMaxCost(t *testing.T) {
+	t.Parallel()
+
+	p, err := NewParser(Def{
+		Functions: map[string]any{
+			"matches": func(pattern, s string) BoolPredicate {
+				return func() bool { return strings.Contains(s, pattern) }
+			},
+		},
+		GetIdentifier: func(selector []string) (any, error) {
+			return "value", nil
+		},
+		FunctionInfo: map[string]FunctionInfo{
+			"matches": {Cost: Linear(2, 1, 1)},
+		},
+		MaxCost:     50,
+		CostOptions: CostOptions{MaxLength: 100},
+	})
+	require.NoError(t, err)
+
+	_, err = p.Parse(`matches("val", x)`)
+	require.True(t, trace.IsLimitExceeded(err), "unexpected error %v", err)
+	require.Contains(t, err.Error(), "expression cost 103 is over the limit of 50")
+
+	out, err := p.Parse(`matches(x, "value")`)
+	require.NoError(t, err)
+	require.True(t, out.(BoolPredicate)())
+}
@@ -0,0 +1,420 @@
+package predicate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"github.com/gravitational/trace"
+	"github.com/vulcand/predicate/internal/astutil"
+)
+
+// ExplainOptions configures Explain.
+type ExplainOptions struct {
+	// Redact returns true for identifiers whose values must not be shown,
+	// e.g. secrets. Values computed from redacted values, and errors of
+	// operators and functions called with them, are redacted as well.
+	Redact func(selector []string) bool
+	// ShowDerivedBooleans shows booleans computed from redacted values,
+	// e.g. the result of token == "x". They are redacted by default, as
+	// they reveal information about the redacted values.
+	ShowDerivedBooleans bool
+}
+
+// Trace is a node of the evaluation trace built by Explain, there is a node
+// for every subexpression except parentheses.
+type Trace struct {
+	// Expr is the text of the subexpression as written.
+	Expr string
+	// Span is the location of the subexpression.
+	Span Span
+	// Value is the value of the subexpression, boolean predicates are
+	// called once and their results recorded, as Tri for three-valued
+	// ones. It is nil if the value is redacted.
+	Value any
+	// Redacted is set if the value is hidden.
+	Redacted bool
+	// Err is the error of the identifier lookup, the operator or the
+	// function call of this subexpression.
+	Err error
+	// ShortCircuited is set if the value of the subexpression did not
+	// matter, because the other operand of && or || decided the result.
+	// It is evaluated anyway, as Parse does, and its value and errors
+	// are recorded.
+	ShortCircuited bool
+	// Deciding is set on operands of &&, || and ! that decided the result,
+	// e.g. the false operand of &&.
+	Deciding bool
+	// Children are the operands and arguments of the subexpression.
+	Children []*Trace
+
+	node ast.Expr
+}
+
+// Explain evaluates the expression like Parse and its result, recording the
+// value of every subexpression. Operators, including &&, || and !, are
+// called from Def.Operators, and Def.ThreeValued and Def.MaxCost apply.
+// Right operands of && and || whose value did not matter, as the left
+// operand decided the result, are marked as short-circuited. If the
+// evaluation fails, the error is returned together with the trace, and
+// is recorded in the node that caused it.
+func (d Def) Explain(in string, opts ExplainOptions) (*Trace, error) {
+	expr, err := parser.ParseExpr(in)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.checkCost(expr); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	e := &explainer{p: &predicateParser{d: d}, in: in, opts: opts}
+	t, _, err := e.eval(expr)
+	if err != nil {
+		return t, withPosition(in, err)
+	}
+	return t, nil
+}
+
+// DecidingClauses returns the subexpressions that decided the result, going
+// down through deciding operands of &&, || and !, e.g. env == "prod" for
+// env == "prod" && level > 3 if the environment is not production.
+func (t *Trace) DecidingClauses() []*Trace {
+	if !isLogicalNode(t.node) {
+		return []*Trace{t}
+	}
+	var out []*Trace
+	for _, c := range t.Children {
+		if c.Deciding {
+			out = append(out, c.DecidingClauses()...)
+		}
+	}
+	return out
+}
+
+// String renders the trace as an indented tree, one subexpression per line,
+// deciding operands are marked with *.
+func (t *Trace) String() string {
+	var sb strings.Builder
+	t.write(&sb, "")
+	return sb.String()
+}
+
+func (t *Trace) write(sb *strings.Builder, indent string) {
+	sb.WriteString(indent)
+	if t.Deciding {
+		sb.WriteString("* ")
+	} else if indent != "" {
+		sb.WriteString("  ")
+	}
+	sb.WriteString(t.Expr)
+	switch {
+	case t.Err != nil:
+		fmt.Fprintf(sb, " => error: %v", t.Err)
+	case t.Redacted:
+		sb.WriteString(" => <redacted>")
+	case t.Value != nil:
+		fmt.Fprintf(sb, " => %v", formatTraceValue(t.Value))
+	}
+	if t.ShortCircuited {
+		sb.WriteString(" (short-circuited)")
+	}
+	sb.WriteString("\n")
+	for _, c := range t.Children {
+		c.write(sb, indent+"  ")
+	}
+}
+
+func formatTraceValue(v any) string {
+	switch val := v.(type) {
+	case string, []string:
+		return fmt.Sprintf("%q", val)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+func isLogicalNode(expr ast.Expr) bool {
+	switch n := expr.(type) {
+	case *ast.BinaryExpr:
+		return n.Op == token.LAND || n.Op == token.LOR
+	case *ast.UnaryExpr:
+		return n.Op == token.NOT
+	default:
+		return false
+	}
+}
+
+type explainer struct {
+	p    *predicateParser
+	in   string
+	opts ExplainOptions
+}
+
+func (e *explainer) newTrace(expr ast.Expr) *Trace {
+	span := spanOf(e.in, expr)
+	return &Trace{
+		Expr: e.in[span.Start.Offset:span.End.Offset],
+		Span: span,
+		node: expr,
+	}
+}
+
+// eval returns the trace of the expression and its value. Errors are
+// recorded in the trace of the subexpression that caused them and
+// returned to all parents.
+func (e *explainer) eval(expr ast.Expr) (*Trace, any, error) {
+	expr = astutil.Unparen(expr)
+	t := e.newTrace(expr)
+	var (
+		val any
+		err error
+	)
+	switch n := expr.(type) {
+	case *ast.BasicLit:
+		val, err = literalToValue(n)
+
+	case *ast.Ident:
+		val, err = e.identifier(t, n, []string{n.Name})
+
+	case *ast.SelectorExpr:
+		var fields []string
+		fields, err = evaluateSelector(n, []string{})
+		if err == nil {
+			val, err = e.identifier(t, n, fields)
+		}
+
+	case *ast.IndexExpr:
+		if e.p.d.GetProperty == nil {
+			err = trace.NotFound("properties are not supported")
+			break
+		}
+		values, childErr := e.children(t, n.X, n.Index)
+		if childErr != nil {
+			return t, nil, childErr
+		}
+		if e.p.d.ThreeValued && anyUnknown(values) {
+			val = unknown{}
+			break
+		}
+		val, err = e.p.d.GetProperty(values[0], values[1])
+		if err != nil {
+			val, err = e.p.unknownIdentifier(n, err)
+		}
+
+	case *ast.UnaryExpr:
+		return e.evalUnary(t, n)
+
+	case *ast.BinaryExpr:
+		if n.Op == token.LAND || n.Op == token.LOR {
+			return e.evalLogical(t, n)
+		}
+		values, childErr := e.children(t, n.X, n.Y)
+		if childErr != nil {
+			return t, nil, childErr
+		}
+		var ok bool
+		if val, ok, err = e.p.joinTri(n.Op, values[0], values[1]); !ok {
+			val, err = e.p.joinPredicates(n.Op, values[0], values[1])
+		}
+
+	case *ast.CallExpr:
+		var (
+			name string
+			fn   any
+			args []ast.Expr
+		)
+		name, fn, args, err = e.p.getFunctionAndArgs(n)
+		if err != nil {
+			break
+		}
+		var prepared map[int]any
+		prepared, err = e.p.prepareLiterals(name, fn, args)
+		if err != nil {
+			break
+		}
+		values, childErr := e.children(t, args...)
+		if childErr != nil {
+			return t, nil, childErr
+		}
+		if e.p.d.ThreeValued && anyUnknown(values) {
+			val = unknown{}
+			break
+		}
+		for i, v := range prepared {
+			values[i] = v
+		}
+		val, err = callFunction(fn, values)
+
+	default:
+		err = trace.BadParameter("%T is not supported", expr)
+	}
+	return e.finish(t, val, err)
+}
+
+// finish records the value or the error in the trace, and returns them.
+// Predicates are called once, parents get predicates returning the
+// recorded results.
+func (e *explainer) finish(t *Trace, val any, err error) (*Trace, any, error) {
+	derived := false
+	for _, c := range t.Children {
+		derived = derived || c.Redacted
+	}
+	if err != nil {
+		if t.Redacted || derived {
+			// Errors may contain the values operators and functions
+			// were called with.
+			err = errorOfKind(err, "the error is redacted, it may contain redacted values")
+			t.Redacted = true
+		}
+		t.Err = err
+		return t, nil, trace.Wrap(err)
+	}
+	value, isBool := traceValue(val)
+	if derived && !(isBool && e.opts.ShowDerivedBooleans) {
+		t.Redacted = true
+	}
+	if !t.Redacted {
+		t.Value = value
+	}
+	return t, settled(val, value), nil
+}
+
+// settled returns predicates as predicates of the same type returning
+// the value, their recorded result, and other values as they are.
+func settled(val, value any) any {
+	switch val.(type) {
+	case BoolPredicate:
+		b := value.(bool)
+		return BoolPredicate(func() bool { return b })
+	case func() bool:
+		b := value.(bool)
+		return func() bool { return b }
+	case TriPredicate:
+		tri := value.(Tri)
+		return TriPredicate(func() Tri { return tri })
+	case func() Tri:
+		tri := value.(Tri)
+		return func() Tri { return tri }
+	default:
+		return val
+	}
+}
+
+// traceValue returns the value to record in the trace, the result of
+// boolean and three-valued predicates, and true if it is a boolean.
+func traceValue(val any) (any, bool) {
+	if isTri(val) {
+		tri, _ := toTri(val)
+		return tri(), true
+	}
+	if b, ok := toBool(val); ok {
+		return b, true
+	}
+	return val, false
+}
+
+func (e *explainer) identifier(t *Trace, node ast.Expr, selector []string) (any, error) {
+	if e.p.d.GetIdentifier == nil {
+		return e.p.unknownIdentifier(node, trace.NotFound("%v is not defined", strings.Join(selector, ".")))
+	}
+	val, err := e.p.d.GetIdentifier(selector)
+	if err != nil {
+		return e.p.unknownIdentifier(node, err)
+	}
+	if e.opts.Redact != nil && e.opts.Redact(selector) {
+		t.Redacted = true
+	}
+	return val, nil
+}
+
+// children evaluates the operands and adds their traces to t.
+func (e *explainer) children(t *Trace, exprs ...ast.Expr) ([]any, error) {
+	values := make([]any, len(exprs))
+	for i, expr := range exprs {
+		c, val, err := e.eval(expr)
+		t.Children = append(t.Children, c)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = val
+	}
+	return values, nil
+}
+
+// evalUnary evaluates unary operators the same way Parse does: the
+// operator is looked up first, unless the operand may be three-valued.
+func (e *explainer) evalUnary(t *Trace, n *ast.UnaryExpr) (*Trace, any, error) {
+	fn, opErr := e.p.getJoinFunction(n.Op)
+	if opErr != nil && !e.p.d.ThreeValued {
+		return e.finish(t, nil, opErr)
+	}
+	values, err := e.children(t, n.X)
+	if err != nil {
+		return t, nil, err
+	}
+	if n.Op == token.NOT {
+		t.Children[0].Deciding = true
+	}
+	if val, ok := e.p.notTri(n.Op, values[0]); ok {
+		return e.finish(t, val, nil)
+	}
+	if opErr != nil {
+		return e.finish(t, nil, opErr)
+	}
+	val, err := callFunction(fn, values)
+	return e.finish(t, val, err)
+}
+
+// evalLogical evaluates both operands of && and ||, as Parse does, and
+// calls the operator with them.
+func (e *explainer) evalLogical(t *Trace, n *ast.BinaryExpr) (*Trace, any, error) {
+	values, err := e.children(t, n.X, n.Y)
+	if err != nil {
+		return t, nil, err
+	}
+	val, ok, err := e.p.joinTri(n.Op, values[0], values[1])
+	if !ok {
+		val, err = e.p.joinPredicates(n.Op, values[0], values[1])
+	}
+	if err != nil {
+		return e.finish(t, nil, err)
+	}
+	t, val, err = e.finish(t, val, nil)
+	markDeciding(t, n.Op, values[0], values[1], val)
+	return t, val, err
+}
+
+// markDeciding marks the operands of && or || that decided the result,
+// the right operand is short-circuited if the left one decided it alone.
+// Operands are not marked if the operator does not have its usual meaning.
+// The values are settled, so the predicates are not called again.
+func markDeciding(t *Trace, op token.Token, xv, yv, val any) {
+	xt, xok := toTri(xv)
+	yt, yok := toTri(yv)
+	vt, vok := toTri(val)
+	if !xok || !yok || !vok {
+		return
+	}
+	x, y := xt(), yt()
+	// The result of && is decided by a false operand, and of || by a true one.
+	decider, result := TriFalse, AndTri(xt, yt)()
+	if op == token.LOR {
+		decider, result = TriTrue, OrTri(xt, yt)()
+	}
+	if vt() != result {
+		return
+	}
+	switch {
+	case x == decider:
+		t.Children[0].Deciding = true
+		t.Children[1].ShortCircuited = true
+	case y == decider:
+		t.Children[1].Deciding = true
+	default:
+		// Without a deciding operand, operands with the value
+		// of the result decided it.
+		t.Children[0].Deciding = x == result
+		t.Children[1].Deciding = y == result
+	}
+}
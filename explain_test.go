@@ -0,0 +1,231 @@
+package predicate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func explainDef() Def {
+	values := map[string]any{
+		"env":          "dev",
+		"level":        5,
+		"roles":        []string{"dev"},
+		"user.token":   "secret",
+		"user.enabled": BoolPredicate(func() bool { return true }),
+	}
+	return Def{
+		Operators: Operators{
+			AND: And,
+			OR:  Or,
+			NOT: Not,
+			EQ:  EqualValues,
+			GT:  Greater,
+		},
+		Functions: map[string]any{
+			"contains": Contains,
+			"lower":    strings.ToLower,
+			"check": func(s string) (bool, error) {
+				return false, trace.BadParameter("invalid value %q", s)
+			},
+		},
+		GetIdentifier: func(selector []string) (any, error) {
+			val, ok := values[strings.Join(selector, ".")]
+			if !ok {
+				return nil, trace.NotFound("%v is not found", strings.Join(selector, "."))
+			}
+			return val, nil
+		},
+	}
+}
+
+func TestExplain(t *testing.T) {
+	t.Parallel()
+
+	tr, err := explainDef().Explain(`env == "prod" && level > 3 || (contains(roles, "dev") && !(level > 10))`, ExplainOptions{})
+	require.NoError(t, err)
+	require.Equal(t, true, tr.Value)
+	require.Equal(t, `env == "prod" && level > 3 || (contains(roles, "dev") && !(level > 10)) => true
+    env == "prod" && level > 3 => false
+    * env == "prod" => false
+        env => "dev"
+        "prod" => "prod"
+      level > 3 => true (short-circuited)
+        level => 5
+        3 => 3
+  * contains(roles, "dev") && !(level > 10) => true
+    * contains(roles, "dev") => true
+        roles => ["dev"]
+        "dev" => "dev"
+    * !(level > 10) => true
+      * level > 10 => false
+          level => 5
+          10 => 10
+`, tr.String())
+
+	left := tr.Children[0]
+	require.Equal(t, Span{
+		Start: Position{Offset: 0, Line: 1, Column: 1},
+		End:   Position{Offset: 26, Line: 1, Column: 27},
+	}, left.Span)
+	// Short-circuited operands keep their values.
+	require.True(t, left.Children[1].ShortCircuited)
+	require.Equal(t, true, left.Children[1].Value)
+
+	var deciding []string
+	for _, c := range tr.DecidingClauses() {
+		deciding = append(deciding, c.Expr)
+	}
+	require.Equal(t, []string{`contains(roles, "dev")`, `level > 10`}, deciding)
+}
+
+func TestExplainDeciding(t *testing.T) {
+	t.Parallel()
+
+	tr, err := explainDef().Explain(`level > 3 && env == "prod"`, ExplainOptions{})
+	require.NoError(t, err)
+	require.Equal(t, false, tr.Value)
+	require.False(t, tr.Children[0].Deciding)
+	require.True(t, tr.Children[1].Deciding)
+	require.Len(t, tr.DecidingClauses(), 1)
+	require.Equal(t, `env == "prod"`, tr.DecidingClauses()[0].Expr)
+}
+
+func TestExplainPredicateCalls(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	d := explainDef()
+	getIdentifier := d.GetIdentifier
+	d.GetIdentifier = func(selector []string) (any, error) {
+		if strings.Join(selector, ".") == "user.locked" {
+			return BoolPredicate(func() bool {
+				calls++
+				return true
+			}), nil
+		}
+		return getIdentifier(selector)
+	}
+	tr, err := d.Explain(`!user.locked && env == "dev" || user.locked`, ExplainOptions{})
+	require.NoError(t, err)
+	require.Equal(t, true, tr.Value)
+	require.True(t, tr.Children[0].Children[1].ShortCircuited)
+	// Predicates are called once per occurrence, whatever the
+	// number of operators their results go through.
+	require.Equal(t, 2, calls)
+}
+
+func TestExplainRedact(t *testing.T) {
+	t.Parallel()
+
+	redact := func(selector []string) bool {
+		return selector[0] == "user"
+	}
+	tr, err := explainDef().Explain(`lower(user.token) == "secret" && user.enabled`, ExplainOptions{Redact: redact})
+	require.NoError(t, err)
+	require.Equal(t, `lower(user.token) == "secret" && user.enabled => <redacted>
+  * lower(user.token) == "secret" => <redacted>
+      lower(user.token) => <redacted>
+        user.token => <redacted>
+      "secret" => "secret"
+  * user.enabled => <redacted>
+`, tr.String())
+
+	// Booleans computed from redacted values can be shown,
+	// redacted identifiers stay redacted.
+	tr, err = explainDef().Explain(`lower(user.token) == "secret" && user.enabled`, ExplainOptions{
+		Redact:              redact,
+		ShowDerivedBooleans: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, `lower(user.token) == "secret" && user.enabled => true
+  * lower(user.token) == "secret" => true
+      lower(user.token) => <redacted>
+        user.token => <redacted>
+      "secret" => "secret"
+  * user.enabled => <redacted>
+`, tr.String())
+
+	// Errors of functions called with redacted values are redacted.
+	tr, err = explainDef().Explain(`check(user.token)`, ExplainOptions{Redact: redact})
+	require.True(t, trace.IsBadParameter(err), "unexpected error %v", err)
+	require.NotContains(t, err.Error(), "secret")
+	require.True(t, tr.Redacted)
+	require.NotContains(t, tr.Err.Error(), "secret")
+	require.NotContains(t, tr.String(), "secret")
+
+	_, err = explainDef().Explain(`check(user.token)`, ExplainOptions{})
+	require.Contains(t, err.Error(), "secret")
+}
+
+func TestExplainError(t *testing.T) {
+	t.Parallel()
+
+	tr, err := explainDef().Explain(`env == "dev" && missing == "a"`, ExplainOptions{})
+	require.True(t, trace.IsNotFound(err), "unexpected error %v", err)
+	require.NotNil(t, tr)
+	require.Nil(t, tr.Value)
+	missing := tr.Children[1].Children[0]
+	require.Equal(t, "missing", missing.Expr)
+	require.True(t, trace.IsNotFound(missing.Err), "unexpected error %v", missing.Err)
+	require.Contains(t, tr.String(), "missing => error: missing is not found")
+}
+
+func TestExplainLikeParse(t *testing.T) {
+	t.Parallel()
+
+	// Short-circuited operands are evaluated, as Parse does.
+	d := explainDef()
+	tr, err := d.Explain(`env == "dev" || missing == "a"`, ExplainOptions{})
+	require.True(t, trace.IsNotFound(err), "unexpected error %v", err)
+	require.False(t, tr.Children[1].ShortCircuited)
+
+	// Logical operators are called from Def.Operators.
+	d.Operators.NOT = nil
+	_, err = d.Explain(`!(env == "dev")`, ExplainOptions{})
+	require.True(t, trace.IsBadParameter(err), "unexpected error %v", err)
+	require.Contains(t, err.Error(), "! is not supported")
+
+	d = explainDef()
+	d.Operators.OR = func(a, b BoolPredicate) BoolPredicate {
+		return func() bool { return a() != b() }
+	}
+	tr, err = d.Explain(`env == "dev" || level > 3`, ExplainOptions{})
+	require.NoError(t, err)
+	require.Equal(t, false, tr.Value)
+	// Operands are not marked if the operator is not the usual one.
+	require.False(t, tr.Children[0].Deciding)
+	require.False(t, tr.Children[1].ShortCircuited)
+
+	// The cost limit applies.
+	d = explainDef()
+	d.MaxCost = 1
+	_, err = d.Explain(`env == "dev" && level > 3`, ExplainOptions{})
+	require.True(t, trace.IsLimitExceeded(err), "unexpected error %v", err)
+}
+
+func TestExplainThreeValued(t *testing.T) {
+	t.Parallel()
+
+	d := explainDef()
+	d.ThreeValued = true
+	tr, err := d.Explain(`missing == "a" || env == "dev"`, ExplainOptions{})
+	require.NoError(t, err)
+	require.Equal(t, `missing == "a" || env == "dev" => true
+    missing == "a" => unknown
+      missing => unknown
+      "a" => "a"
+  * env == "dev" => true
+      env => "dev"
+      "dev" => "dev"
+`, tr.String())
+	require.Equal(t, TriUnknown, tr.Children[0].Value)
+
+	tr, err = d.Explain(`missing == "a" && !(env == "prod")`, ExplainOptions{})
+	require.NoError(t, err)
+	require.Equal(t, TriUnknown, tr.Value)
+	require.True(t, tr.Children[0].Deciding)
+	require.False(t, tr.Children[1].Deciding)
+}
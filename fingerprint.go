@@ -0,0 +1,282 @@
+package predicate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gravitational/trace"
+	"github.com/vulcand/predicate/internal/astutil"
+)
+
+// FingerprintVersion prefixes fingerprints. It changes only when the
+// language or the normalization changes, so fingerprints stored with
+// one version of the library stay valid with the next.
+const FingerprintVersion = "v1"
+
+// FingerprintOptions configures the normalization of fingerprinted expressions.
+type FingerprintOptions struct {
+	// SortCommutative sorts operands of &&, ||, == and !=, so a && b and
+	// b && a have the same fingerprint. It assumes operands have no side
+	// effects and == and != are symmetric, as they are for Equals.
+	SortCommutative bool
+}
+
+// Fingerprint returns a stable hash of the normalized expression, e.g. to
+// dedupe rules or to use them as cache keys. Expressions that differ only in
+// whitespace, redundant parentheses, grouping of && and || chains or the
+// spelling of literals, e.g. "a" and `a`, have the same fingerprint.
+// Fingerprints look like v1:<hex sha256>, see FingerprintVersion.
+func Fingerprint(in string, opts FingerprintOptions) (string, error) {
+	canonical, err := Canonical(in, opts)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(FingerprintVersion + "\n" + canonical))
+	return FingerprintVersion + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+// Canonical returns the normalized expression Fingerprint hashes.
+// Expressions the parser does not support are rejected.
+func Canonical(in string, opts FingerprintOptions) (string, error) {
+	expr, err := parser.ParseExpr(in)
+	if err != nil {
+		return "", err
+	}
+	if err := checkSupported(expr); err != nil {
+		return "", trace.Wrap(err)
+	}
+	return canonicalString(canonicalExpr(expr, opts)), nil
+}
+
+// canonicalExpr returns a copy of the expression without parentheses,
+// with literals in canonical form, chains of && and || grouped from the
+// left, and operands sorted if requested.
+func canonicalExpr(expr ast.Expr, opts FingerprintOptions) ast.Expr {
+	switch n := expr.(type) {
+	case *ast.ParenExpr:
+		return canonicalExpr(n.X, opts)
+
+	case *ast.BasicLit:
+		return &ast.BasicLit{Kind: n.Kind, Value: canonicalLiteral(n)}
+
+	case *ast.BinaryExpr:
+		if n.Op == token.LAND || n.Op == token.LOR {
+			operands := astutil.LogicalOperands(n.Op, n)
+			for i, operand := range operands {
+				operands[i] = canonicalExpr(operand, opts)
+			}
+			if opts.SortCommutative {
+				sortExprs(operands)
+			}
+			return joinExprs(operands, n.Op)
+		}
+		operands := []ast.Expr{canonicalExpr(n.X, opts), canonicalExpr(n.Y, opts)}
+		if opts.SortCommutative && (n.Op == token.EQL || n.Op == token.NEQ) {
+			sortExprs(operands)
+		}
+		return &ast.BinaryExpr{X: operands[0], Op: n.Op, Y: operands[1]}
+
+	case *ast.UnaryExpr:
+		return &ast.UnaryExpr{Op: n.Op, X: canonicalExpr(n.X, opts)}
+
+	case *ast.IndexExpr:
+		return &ast.IndexExpr{X: canonicalExpr(n.X, opts), Index: canonicalExpr(n.Index, opts)}
+
+	case *ast.SelectorExpr:
+		return &ast.SelectorExpr{X: canonicalExpr(n.X, opts), Sel: n.Sel}
+
+	case *ast.CallExpr:
+		args := make([]ast.Expr, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = canonicalExpr(arg, opts)
+		}
+		return &ast.CallExpr{Fun: canonicalExpr(n.Fun, opts), Args: args}
+
+	default:
+		return expr
+	}
+}
+
+// canonicalLiteral returns the literal in canonical form: strings are
+// double quoted, integers are decimal and floats are written with the
+// fewest digits that read back to the same value. Literals the parser
+// can not read are kept as written.
+func canonicalLiteral(lit *ast.BasicLit) string {
+	switch lit.Kind {
+	case token.STRING:
+		s, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return lit.Value
+		}
+		return canonicalQuote(s)
+	case token.INT:
+		// The parser reads integers in base 10, e.g. 010 is 10.
+		i, err := strconv.ParseUint(lit.Value, 10, 64)
+		if err != nil {
+			return lit.Value
+		}
+		return strconv.FormatUint(i, 10)
+	case token.FLOAT:
+		f, err := strconv.ParseFloat(lit.Value, 64)
+		if err != nil {
+			return lit.Value
+		}
+		out := strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(out, ".e") {
+			// Keep the literal a float, e.g. 4.0 and not 4.
+			out += ".0"
+		}
+		return out
+	default:
+		return lit.Value
+	}
+}
+
+// canonicalQuote returns the string double quoted. Unlike strconv.Quote,
+// the output does not depend on the Unicode version: only quotes,
+// backslashes, ASCII control characters, invalid UTF-8 and byte order
+// marks are escaped.
+func canonicalQuote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '"' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r < 0x20 || r == 0x7f || (r == utf8.RuneError && size == 1):
+			fmt.Fprintf(&sb, "\\x%02x", s[i])
+		case r == '\uFEFF':
+			sb.WriteString(`\uFEFF`)
+		default:
+			sb.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// sortExprs sorts expressions by their canonical text.
+func sortExprs(exprs []ast.Expr) {
+	keys := make(map[ast.Expr]string, len(exprs))
+	for _, expr := range exprs {
+		keys[expr] = canonicalString(expr)
+	}
+	sort.SliceStable(exprs, func(i, j int) bool {
+		return keys[exprs[i]] < keys[exprs[j]]
+	})
+}
+
+// canonicalString returns the text of the canonical expression. It does not
+// use the expression printer, so changes to it do not change fingerprints,
+// changes to its output require a new FingerprintVersion.
+func canonicalString(expr ast.Expr) string {
+	var sb strings.Builder
+	writeCanonical(&sb, expr)
+	return sb.String()
+}
+
+// Precedences of canonical expressions, fixed here rather than taken from
+// go/token, so the canonical text only changes with FingerprintVersion.
+const (
+	canonicalOrPrec = iota + 1
+	canonicalAndPrec
+	canonicalComparisonPrec
+	canonicalAddPrec
+	canonicalMulPrec
+	canonicalUnaryPrec
+	canonicalPrimaryPrec
+)
+
+// canonicalPrec returns the precedence of the outermost operator
+// of the canonical expression.
+func canonicalPrec(expr ast.Expr) int {
+	switch n := expr.(type) {
+	case *ast.ParenExpr:
+		return canonicalPrec(n.X)
+	case *ast.BinaryExpr:
+		return canonicalBinaryPrec(n.Op)
+	case *ast.UnaryExpr:
+		return canonicalUnaryPrec
+	default:
+		return canonicalPrimaryPrec
+	}
+}
+
+func canonicalBinaryPrec(op token.Token) int {
+	switch op {
+	case token.LOR:
+		return canonicalOrPrec
+	case token.LAND:
+		return canonicalAndPrec
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		return canonicalComparisonPrec
+	case token.ADD, token.SUB, token.OR, token.XOR:
+		return canonicalAddPrec
+	default:
+		return canonicalMulPrec
+	}
+}
+
+// writeCanonical writes the expression with single spaces around binary
+// operators, ", " between arguments, and parentheses only where operator
+// precedence requires them.
+func writeCanonical(sb *strings.Builder, expr ast.Expr) {
+	switch n := expr.(type) {
+	case *ast.ParenExpr:
+		writeCanonical(sb, n.X)
+	case *ast.BinaryExpr:
+		prec := canonicalBinaryPrec(n.Op)
+		writeCanonicalOperand(sb, n.X, prec)
+		sb.WriteString(" ")
+		sb.WriteString(n.Op.String())
+		sb.WriteString(" ")
+		writeCanonicalOperand(sb, n.Y, prec+1)
+	case *ast.UnaryExpr:
+		sb.WriteString(n.Op.String())
+		writeCanonicalOperand(sb, n.X, canonicalUnaryPrec)
+	case *ast.BasicLit:
+		sb.WriteString(n.Value)
+	case *ast.Ident:
+		sb.WriteString(n.Name)
+	case *ast.SelectorExpr:
+		writeCanonicalOperand(sb, n.X, canonicalPrimaryPrec)
+		sb.WriteString(".")
+		sb.WriteString(n.Sel.Name)
+	case *ast.IndexExpr:
+		writeCanonicalOperand(sb, n.X, canonicalPrimaryPrec)
+		sb.WriteString("[")
+		writeCanonical(sb, n.Index)
+		sb.WriteString("]")
+	case *ast.CallExpr:
+		writeCanonicalOperand(sb, n.Fun, canonicalPrimaryPrec)
+		sb.WriteString("(")
+		for i, arg := range n.Args {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			writeCanonical(sb, arg)
+		}
+		sb.WriteString(")")
+	}
+}
+
+func writeCanonicalOperand(sb *strings.Builder, expr ast.Expr, minPrec int) {
+	if canonicalPrec(expr) < minPrec {
+		sb.WriteString("(")
+		writeCanonical(sb, expr)
+		sb.WriteString(")")
+		return
+	}
+	writeCanonical(sb, expr)
+}
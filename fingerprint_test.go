@@ -0,0 +1,93 @@
+package predicate
+
+import (
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonical(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		in     string
+		sorted bool
+		expect string
+	}{
+		{in: `  a&&(b)  `, expect: `a && b`},
+		{in: `a && (b && c)`, expect: `a && b && c`},
+		{in: `(b) && a`, expect: `b && a`},
+		{in: `(b) && a`, sorted: true, expect: `a && b`},
+		{in: `c || (b && a) || a`, sorted: true, expect: `a || a && b || c`},
+		{in: `!(b || a)`, sorted: true, expect: `!(a || b)`},
+		{in: `user.name == "x"`, sorted: true, expect: `"x" == user.name`},
+		{in: `a < b`, sorted: true, expect: `a < b`},
+		{in: "f(`raw`, 16, 1e3) && m['k'][\"v\"]", expect: `f("raw", 16, 1000.0) && m['k']["v"]`},
+		{in: `a == 010 && b == 1.50`, expect: `a == 10 && b == 1.5`},
+		{in: "a == `café\t\\`", expect: `a == "café\x09\\"`},
+		{in: `a == "\ufeff\x7f"`, expect: `a == "\uFEFF\x7f"`},
+		{in: `!(a && b) || (c || d) && e`, expect: `!(a && b) || (c || d) && e`},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			out, err := Canonical(tc.in, FingerprintOptions{SortCommutative: tc.sorted})
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, out)
+		})
+	}
+
+	_, err := Canonical(`a + b`, FingerprintOptions{})
+	require.True(t, trace.IsBadParameter(err), "unexpected error %v", err)
+}
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+
+	sorted := FingerprintOptions{SortCommutative: true}
+
+	a, err := Fingerprint(`a && b`, sorted)
+	require.NoError(t, err)
+	b, err := Fingerprint("(b) &&\n\ta", sorted)
+	require.NoError(t, err)
+	require.Equal(t, a, b)
+
+	c, err := Fingerprint("(b) &&\n\ta", FingerprintOptions{})
+	require.NoError(t, err)
+	require.NotEqual(t, a, c)
+
+	// Fingerprints must not change across library versions,
+	// update FingerprintVersion if this test breaks.
+	for _, tc := range []struct {
+		in     string
+		expect string
+	}{
+		{
+			in:     `(contains(user.roles, "admin")) && "alice" == user.name`,
+			expect: "v1:b51a2e6402d95caa8bea4f733199e9debb99095ffa85da0e9d3321ca374f1dbd",
+		},
+		{
+			in:     `a || b && c`,
+			expect: "v1:26ab6dc454eaf61f7583c0f63e4d7b4131e5039eb0d01eff819387b4b1a40820",
+		},
+		{
+			in:     `(a || b) && !(c == 010)`,
+			expect: "v1:b7e6173269c900922cdc7d90bc02e500b0a6adf24d82c15e470f740173ba566b",
+		},
+		{
+			in:     "name == `café\t\"q\"` && score >= 1.50",
+			expect: "v1:9f9a6fe97811d8edd995e2296a098696da3c111f1076aa24cc492acba4f9f4d5",
+		},
+		{
+			in:     `f(1e3, 2.0, 0.5)[x] != "\x00\\"`,
+			expect: "v1:cd3783f79f74b3002f07913589b6fb7cf248ee8918d144290e2559e36f7be4db",
+		},
+		{
+			in:     `b != a || a == b`,
+			expect: "v1:b74a9e2c123d3f47d3e6ccffff05cdbb189dc682abcd5ca661cde0cff4737426",
+		},
+	} {
+		out, err := Fingerprint(tc.in, sorted)
+		require.NoError(t, err)
+		require.Equal(t, tc.expect, out, tc.in)
+	}
+}
@@ -0,0 +1,160 @@
+package predicate
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gravitational/trace"
+	"github.com/vulcand/predicate/internal/astutil"
+)
+
+const (
+	// FormatWidth is the line width after which Format wraps && and || chains.
+	FormatWidth = 80
+	// formatTabWidth is the width of a tab when measuring lines.
+	formatTabWidth = 8
+)
+
+// Format returns the canonical form of the expression, like gofmt does for
+// Go code: binary operators are surrounded by single spaces, arguments are
+// separated by ", ", parentheses are kept only where precedence requires
+// them, and literals are kept as written, e.g. 0x10 or `raw`. Chains of &&
+// and || longer than FormatWidth are wrapped with one operand per line,
+// operators at line ends and continuation lines indented with a tab:
+//
+//	user.traits.team == "sre" && resource.env == "prod" ||
+//		contains(user.roles, "admin")
+//
+// Formatting a formatted expression returns it unchanged. Expressions the
+// parser does not support, e.g. a + b or []string{}, are rejected.
+func Format(in string) (string, error) {
+	expr, err := parser.ParseExpr(in)
+	if err != nil {
+		return "", err
+	}
+	if err := checkSupported(expr); err != nil {
+		return "", trace.Wrap(err)
+	}
+	f := &formatter{}
+	f.writeFormatted(expr, 0, 0)
+	return f.sb.String(), nil
+}
+
+// IsFormatted returns true if the expression is in the form returned by Format.
+func IsFormatted(in string) (bool, error) {
+	out, err := Format(in)
+	if err != nil {
+		return false, err
+	}
+	return out == in, nil
+}
+
+// checkSupported returns an error for nodes and operators the parser
+// does not support.
+func checkSupported(expr ast.Expr) error {
+	var err error
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if err != nil {
+			return false
+		}
+		switch n := n.(type) {
+		case nil, *ast.ParenExpr, *ast.BasicLit, *ast.Ident, *ast.SelectorExpr, *ast.IndexExpr:
+		case *ast.BinaryExpr:
+			if !isSupportedOperator(n.Op) {
+				err = trace.BadParameter("%v is not supported", n.Op)
+			}
+		case *ast.UnaryExpr:
+			if n.Op != token.NOT {
+				err = trace.BadParameter("%v is not supported", n.Op)
+			}
+		case *ast.CallExpr:
+			if n.Ellipsis.IsValid() {
+				err = trace.BadParameter("... is not supported")
+			}
+		default:
+			err = trace.BadParameter("%T is not supported", n)
+		}
+		return err == nil
+	})
+	return err
+}
+
+// isSupportedOperator returns true for binary operators of Operators.
+func isSupportedOperator(op token.Token) bool {
+	switch op {
+	case token.LAND, token.LOR, token.GTR, token.GEQ, token.LSS, token.LEQ, token.EQL, token.NEQ:
+		return true
+	default:
+		return false
+	}
+}
+
+// formatter writes formatted expressions and tracks the column of the
+// current line.
+type formatter struct {
+	sb  strings.Builder
+	col int
+}
+
+func (f *formatter) write(s string) {
+	f.sb.WriteString(s)
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		s, f.col = s[i+1:], 0
+	}
+	for _, r := range s {
+		if r == '\t' {
+			f.col += formatTabWidth
+			continue
+		}
+		f.col++
+	}
+}
+
+// writeFormatted writes the expression, wrapping && and || chains that do
+// not fit the line at the indentation depth. suffix is the length of the
+// text that follows the expression on the same line, e.g. " &&" or ")".
+func (f *formatter) writeFormatted(expr ast.Expr, depth, suffix int) {
+	expr = astutil.Unparen(expr)
+	text := astutil.ExprString(expr)
+	bin, ok := expr.(*ast.BinaryExpr)
+	if !ok || (bin.Op != token.LAND && bin.Op != token.LOR) ||
+		f.col+utf8.RuneCountInString(text)+suffix <= FormatWidth {
+		f.write(text)
+		return
+	}
+	prec := bin.Op.Precedence()
+	operands := chainOperands(bin)
+	for i, operand := range operands {
+		minPrec := prec
+		if i > 0 {
+			f.write(" " + bin.Op.String() + "\n" + strings.Repeat("\t", depth+1))
+			// Right operands with the same precedence need parentheses,
+			// see writeExpr.
+			minPrec = prec + 1
+		}
+		// Operands other than the last are followed by the operator.
+		operandSuffix := len(bin.Op.String()) + 1
+		if i == len(operands)-1 {
+			operandSuffix = suffix
+		}
+		if astutil.Precedence(operand) < minPrec {
+			f.write("(")
+			f.writeFormatted(operand, depth+1, operandSuffix+1)
+			f.write(")")
+			continue
+		}
+		f.writeFormatted(operand, depth+1, operandSuffix)
+	}
+}
+
+// chainOperands returns operands of the left associative chain of the
+// binary operator, e.g. a, b and c for a && b && c.
+func chainOperands(bin *ast.BinaryExpr) []ast.Expr {
+	if x, ok := astutil.Unparen(bin.X).(*ast.BinaryExpr); ok && x.Op == bin.Op {
+		return append(chainOperands(x), bin.Y)
+	}
+	return []ast.Expr{bin.X, bin.Y}
+}
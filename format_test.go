@@ -0,0 +1,107 @@
+package predicate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		in     string
+		expect string
+	}{
+		{in: `a&&b`, expect: `a && b`},
+		{in: `  a ==   "b"  `, expect: `a == "b"`},
+		{in: `((a)) && ((b || c))`, expect: `a && (b || c)`},
+		{in: `(a && b) || c`, expect: `a && b || c`},
+		{in: `a && (b && c)`, expect: `a && (b && c)`},
+		{in: `!(a)`, expect: `!a`},
+		{in: `!(a && b)`, expect: `!(a && b)`},
+		{in: `f( a,b ,  c )`, expect: `f(a, b, c)`},
+		{in: `x.f(  )`, expect: `x.f()`},
+		{in: `labels[ "env" ]=="prod"`, expect: `labels["env"] == "prod"`},
+		{in: "x == `raw` && y == 0x1F && z == 1e3 && w == 'c'", expect: "x == `raw` && y == 0x1F && z == 1e3 && w == 'c'"},
+		{
+			in: `user.traits.team == "sre" && resource.env == "prod" || contains(user.roles, "admin")`,
+			expect: `user.traits.team == "sre" && resource.env == "prod" ||
+	contains(user.roles, "admin")`,
+		},
+		{
+			in: `contains(user.traits.logins, "root") && resource.metadata.labels["env"] == "production" && user.name != "guest"`,
+			expect: `contains(user.traits.logins, "root") &&
+	resource.metadata.labels["env"] == "production" &&
+	user.name != "guest"`,
+		},
+		{
+			in: `user.admin || (contains(user.traits.logins, "root") && resource.metadata.labels["env"] == "production")`,
+			expect: `user.admin ||
+	contains(user.traits.logins, "root") &&
+		resource.metadata.labels["env"] == "production"`,
+		},
+		{
+			in: `user.admin && (contains(user.traits.logins, "root") || resource.metadata.labels["environment"] == "production")`,
+			expect: `user.admin &&
+	(contains(user.traits.logins, "root") ||
+		resource.metadata.labels["environment"] == "production")`,
+		},
+		{
+			// The first operand is measured from the start of the line.
+			in: `contains(user.traits.logins, "root") && resource.metadata.labels["e"] == "p" || user.admin`,
+			expect: `contains(user.traits.logins, "root") && resource.metadata.labels["e"] == "p" ||
+	user.admin`,
+		},
+		{
+			// Closing parentheses count towards the line width.
+			in: `user.admin && (contains(user.traits.logins, "root") || resource.labels["env"] == "xyzw")`,
+			expect: `user.admin &&
+	(contains(user.traits.logins, "root") ||
+		resource.labels["env"] == "xyzw")`,
+		},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			out, err := Format(tc.in)
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, out)
+
+			for _, line := range strings.Split(out, "\n") {
+				width := len(strings.ReplaceAll(line, "\t", strings.Repeat(" ", formatTabWidth)))
+				require.LessOrEqual(t, width, FormatWidth, line)
+			}
+
+			// Formatting is stable.
+			again, err := Format(out)
+			require.NoError(t, err)
+			require.Equal(t, out, again)
+
+			ok, err := IsFormatted(out)
+			require.NoError(t, err)
+			require.True(t, ok)
+		})
+	}
+
+	ok, err := IsFormatted(`a&&b`)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, err = Format(`a &&`)
+	require.Error(t, err)
+
+	// Expressions the parser does not support are rejected.
+	for _, in := range []string{
+		`a + b`,
+		`-a`,
+		`a == []string{"b"}`,
+		`f(a...)`,
+		`x.(string)`,
+		`func() bool { return true }()`,
+		`a[1:2]`,
+	} {
+		_, err := Format(in)
+		require.True(t, trace.IsBadParameter(err), "%v: unexpected error %v", in, err)
+	}
+}
@@ -0,0 +1,234 @@
+package predicate
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gravitational/trace"
+)
+
+// GlobOptions configures GlobModule.
+type GlobOptions struct {
+	// CaseInsensitive makes patterns match regardless of letter case.
+	CaseInsensitive bool
+	// CacheSize is the number of compiled patterns kept in the cache
+	// for patterns that are not literals.
+	CacheSize int
+}
+
+// CheckAndSetDefaults checks and sets default values.
+func (o *GlobOptions) CheckAndSetDefaults() error {
+	if o.CacheSize < 0 {
+		return trace.BadParameter("cache size can not be negative")
+	}
+	if o.CacheSize == 0 {
+		o.CacheSize = DefaultRegexCacheSize
+	}
+	return nil
+}
+
+// GlobModule returns wildcard matching functions, available both as
+// functions and as methods:
+//
+//	glob(value, pattern) - returns BoolPredicate, the pattern has to match
+//	                       the whole value, e.g. glob(name, "db-*-prod")
+//	globToRegex(pattern) - returns the equivalent regular expression
+//
+// See GlobToRegex for the pattern syntax.
+func GlobModule(opts GlobOptions) (Module, error) {
+	if err := opts.CheckAndSetDefaults(); err != nil {
+		return Module{}, trace.Wrap(err)
+	}
+	g := &globCompiler{
+		opts:  opts,
+		cache: newRegexCache(opts.CacheSize),
+	}
+	fns := map[string]any{
+		"glob":        g.glob,
+		"globToRegex": GlobToRegex,
+	}
+	return Module{
+		Functions: fns,
+		Methods:   copyFunctions(fns),
+		FunctionInfo: map[string]FunctionInfo{
+			"glob":        {Literals: []LiteralFunc{nil, g.literal}, Pure: true, Cost: globCost},
+			"globToRegex": {Pure: true, Cost: Linear(1, 1, 0)},
+		},
+	}, nil
+}
+
+// globPattern is a glob pattern compiled when the expression was parsed.
+type globPattern struct {
+	re *regexp.Regexp
+}
+
+// globCost is linear in the input and in the pattern, which is
+// converted to a regular expression unless it is a literal.
+var globCost = linearSum(1, 1, 0, 1)
+
+type globCompiler struct {
+	opts  GlobOptions
+	cache *regexCache
+}
+
+func (g *globCompiler) literal(pattern any) (any, error) {
+	re, err := g.regexp(pattern)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &globPattern{re: re}, nil
+}
+
+func (g *globCompiler) regexp(pattern any) (*regexp.Regexp, error) {
+	switch p := pattern.(type) {
+	case *globPattern:
+		return p.re, nil
+	case string:
+		if re, ok := g.cache.get(p); ok {
+			return re, nil
+		}
+		expr, err := GlobToRegex(p)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if g.opts.CaseInsensitive {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, trace.BadParameter("invalid glob pattern %q: %v", p, err)
+		}
+		g.cache.add(p, re)
+		return re, nil
+	default:
+		return nil, trace.BadParameter("expected pattern to be a string, got %T", pattern)
+	}
+}
+
+func (g *globCompiler) glob(value string, pattern any) (BoolPredicate, error) {
+	re, err := g.regexp(pattern)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return func() bool {
+		return re.MatchString(value)
+	}, nil
+}
+
+// GlobToRegex converts the glob pattern to an anchored regular expression,
+// the pattern syntax is:
+//
+//	pattern  meaning
+//	*        matches any sequence of characters, including none
+//	?        matches any single character
+//	[abc]    matches any of the characters, ranges like [a-z] are supported
+//	[!abc]   matches any character except the listed ones, [^abc] works too
+//	{a,b}    matches any of the comma separated alternatives, which may
+//	         contain other wildcards and nested alternatives
+//	\c       matches the character c literally
+//
+// Unlike shell wildcards, * and ? match path separators, so patterns
+// work for labels and names alike.
+func GlobToRegex(pattern string) (string, error) {
+	var sb strings.Builder
+	// (?s) lets wildcards match new lines like any other character.
+	sb.WriteString("(?s)^")
+	depth := 0
+	for i := 0; i < len(pattern); {
+		r, size := utf8.DecodeRuneInString(pattern[i:])
+		i += size
+		switch r {
+		case '\\':
+			if i >= len(pattern) {
+				return "", trace.BadParameter("invalid glob pattern %q: trailing backslash", pattern)
+			}
+			next, size := utf8.DecodeRuneInString(pattern[i:])
+			i += size
+			sb.WriteString(regexp.QuoteMeta(string(next)))
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		case '[':
+			class, size, err := globClass(pattern[i:])
+			if err != nil {
+				return "", trace.BadParameter("invalid glob pattern %q: %v", pattern, err)
+			}
+			i += size
+			sb.WriteString(class)
+		case '{':
+			depth++
+			sb.WriteString("(?:")
+		case ',':
+			if depth > 0 {
+				sb.WriteString("|")
+			} else {
+				sb.WriteString(",")
+			}
+		case '}':
+			if depth == 0 {
+				return "", trace.BadParameter("invalid glob pattern %q: unexpected }", pattern)
+			}
+			depth--
+			sb.WriteString(")")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if depth > 0 {
+		return "", trace.BadParameter("invalid glob pattern %q: missing }", pattern)
+	}
+	sb.WriteString("$")
+	// Classes are passed through, so invalid ones like [z-a]
+	// are only caught by the regular expression parser.
+	if _, err := regexp.Compile(sb.String()); err != nil {
+		return "", trace.BadParameter("invalid glob pattern %q: %v", pattern, err)
+	}
+	return sb.String(), nil
+}
+
+// globClass converts the character class following [ to a regular
+// expression and returns the number of bytes consumed, including the ].
+func globClass(in string) (string, int, error) {
+	var sb strings.Builder
+	sb.WriteString("[")
+	i := 0
+	if i < len(in) && (in[i] == '!' || in[i] == '^') {
+		sb.WriteString("^")
+		i++
+	}
+	start := i
+	for i < len(in) {
+		r, size := utf8.DecodeRuneInString(in[i:])
+		// ] closes the class unless it is the first character.
+		if r == ']' && i > start {
+			sb.WriteString("]")
+			return sb.String(), i + size, nil
+		}
+		i += size
+		switch {
+		case r == '\\':
+			if i >= len(in) {
+				return "", 0, trace.BadParameter("trailing backslash")
+			}
+			next, size := utf8.DecodeRuneInString(in[i:])
+			i += size
+			sb.WriteString(quoteClassRune(next))
+		case r == '-' && i-size > start && i < len(in) && in[i] != ']':
+			sb.WriteString("-")
+		default:
+			sb.WriteString(quoteClassRune(r))
+		}
+	}
+	return "", 0, trace.BadParameter("missing ]")
+}
+
+func quoteClassRune(r rune) string {
+	switch r {
+	case '\\', '[', ']', '^', '-':
+		return `\` + string(r)
+	default:
+		return string(r)
+	}
+}
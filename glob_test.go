@@ -0,0 +1,86 @@
+package predicate
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobToRegex(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		pattern  string
+		match    []string
+		mismatch []string
+	}{
+		{pattern: "db-*-prod", match: []string{"db-a-prod", "db--prod", "db-a/b-prod"}, mismatch: []string{"db-a-prod2", "xdb-a-prod"}},
+		{pattern: "node-?", match: []string{"node-1", "node-é"}, mismatch: []string{"node-", "node-12"}},
+		{pattern: "env-{dev,stage}", match: []string{"env-dev", "env-stage"}, mismatch: []string{"env-prod", "env-{dev,stage}"}},
+		{pattern: "{a,b{1,2}}-*", match: []string{"a-x", "b1-", "b2-y"}, mismatch: []string{"b-x", "c-x"}},
+		{pattern: "[a-c]x", match: []string{"ax", "cx"}, mismatch: []string{"dx", "-x"}},
+		{pattern: "[!a-c]x", match: []string{"dx", "-x"}, mismatch: []string{"ax"}},
+		{pattern: "[]a]", match: []string{"]", "a"}, mismatch: []string{"b"}},
+		{pattern: "[a-]", match: []string{"a", "-"}, mismatch: []string{"b"}},
+		{pattern: `a\*b.c`, match: []string{"a*b.c"}, mismatch: []string{"axb.c", "a*bxc"}},
+		{pattern: "a,b", match: []string{"a,b"}, mismatch: []string{"a"}},
+	} {
+		t.Run(tc.pattern, func(t *testing.T) {
+			expr, err := GlobToRegex(tc.pattern)
+			require.NoError(t, err)
+			re := regexp.MustCompile(expr)
+			for _, v := range tc.match {
+				require.True(t, re.MatchString(v), "%q should match %q", tc.pattern, v)
+			}
+			for _, v := range tc.mismatch {
+				require.False(t, re.MatchString(v), "%q should not match %q", tc.pattern, v)
+			}
+		})
+	}
+
+	for _, pattern := range []string{`a\`, "[abc", "[]", "{a,b", "a}", `[a\`, "[z-a]", "x-[9-0]*"} {
+		_, err := GlobToRegex(pattern)
+		require.True(t, trace.IsBadParameter(err), "pattern %q: unexpected error %v", pattern, err)
+	}
+}
+
+func TestGlobModule(t *testing.T) {
+	t.Parallel()
+
+	values := map[string]any{
+		"name":    "DB-east-Prod",
+		"pattern": "db-*",
+	}
+	glob, err := GlobModule(GlobOptions{})
+	require.NoError(t, err)
+	p := newTestParser(t, Def{}, values, glob)
+	iglob, err := GlobModule(GlobOptions{CaseInsensitive: true})
+	require.NoError(t, err)
+	ip := newTestParser(t, Def{}, values, iglob)
+
+	for _, tc := range []struct {
+		desc   string
+		parser Parser
+		input  string
+		expect any
+	}{
+		{desc: "literal pattern", parser: p, input: `glob(name, "DB-*-Prod")`, expect: true},
+		{desc: "case sensitive", parser: p, input: `glob(name, "db-*-prod")`, expect: false},
+		{desc: "case insensitive", parser: ip, input: `glob(name, "db-*-prod")`, expect: true},
+		{desc: "method", parser: p, input: `name.glob("DB-{east,west}-*")`, expect: true},
+		{desc: "anchored", parser: p, input: `glob(name, "east")`, expect: false},
+		{desc: "dynamic pattern", parser: ip, input: `glob(name, pattern)`, expect: true},
+		{desc: "with operators", parser: p, input: `glob(name, "DB-*") && !glob(name, "*-Dev")`, expect: true},
+		{desc: "to regex", parser: p, input: `globToRegex("a*")`, expect: "(?s)^a.*$"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			require.Equal(t, tc.expect, parseAndEval(t, tc.parser, tc.input))
+		})
+	}
+
+	_, err = p.Parse(`glob(name, "a") && glob(name, "{a,b")`)
+	require.True(t, trace.IsBadParameter(err), "unexpected error %v", err)
+	require.Contains(t, err.Error(), "1:31: invalid glob pattern")
+}
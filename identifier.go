@@ -0,0 +1,350 @@
+package predicate
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/gravitational/trace"
+)
+
+// IdentifierConfig configures identifier lookups created by NewGetIdentifier.
+type IdentifierConfig struct {
+	// Roots maps root identifier names to values,
+	// e.g. "user" and "resource".
+	Roots map[string]any
+	// Tags are tried in order to resolve struct fields,
+	// see GetFieldByTags. Defaults to json.
+	Tags []string
+	// Options control traversal of maps, slices and getters.
+	Options FieldOptions
+	// Allow lists selector paths that can be accessed together with
+	// everything below them, e.g. "user.spec.traits". A "*" segment
+	// in a pattern matches any single segment, selectors can not contain
+	// wildcards. Patterns match the fields selectors resolve to, however
+	// they are spelled. If empty, all paths are allowed.
+	Allow []string
+	// Deny lists selector paths that can not be accessed, neither
+	// directly nor through their parents, e.g. "user.spec.password_hash"
+	// denies both user.spec.password_hash and user.spec.
+	// Deny takes precedence over Allow.
+	Deny []string
+	// Renames maps selector paths used in expressions to the paths
+	// used to look up values, e.g. "user.name" to "user.metadata.name".
+	// A path is allowed if either form is allowed by Allow,
+	// and denied if either form is denied by Deny.
+	Renames map[string]string
+}
+
+// CheckAndSetDefaults checks and sets default values.
+func (c *IdentifierConfig) CheckAndSetDefaults() error {
+	if len(c.Roots) == 0 {
+		return trace.BadParameter("missing parameter Roots")
+	}
+	if len(c.Tags) == 0 {
+		c.Tags = []string{"json"}
+	}
+	for _, path := range append(append([]string{}, c.Allow...), c.Deny...) {
+		if err := checkPath(path); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	for from, to := range c.Renames {
+		if err := checkPath(from); err != nil {
+			return trace.Wrap(err)
+		}
+		if err := checkPath(to); err != nil {
+			return trace.Wrap(err)
+		}
+		root, _, _ := strings.Cut(to, ".")
+		if _, ok := c.Roots[root]; !ok {
+			return trace.BadParameter("rename %q refers to unknown root %q", from, root)
+		}
+	}
+	return nil
+}
+
+func checkPath(path string) error {
+	for _, segment := range strings.Split(path, ".") {
+		if segment == "" {
+			return trace.BadParameter("path %q has an empty segment", path)
+		}
+	}
+	return nil
+}
+
+// NewGetIdentifier returns GetIdentifierFn that resolves selectors like
+// user.spec.traits against the configured roots, e.g.:
+//
+//	getID, err := NewGetIdentifier(IdentifierConfig{
+//		Roots: map[string]any{"user": user, "resource": resource},
+//		Deny:  []string{"user.spec.password_hash"},
+//	})
+//
+// Allow and Deny apply to the fields selectors resolve to, not to their
+// spelling, so with Tags set to json and FieldNameTag the deny above also
+// covers user.spec.PasswordHash, and with getters user.spec.passwordHash
+// resolved by GetPasswordHash(). Access to paths that are not allowed
+// fails with trace.AccessDenied.
+func NewGetIdentifier(cfg IdentifierConfig) (GetIdentifierFn, error) {
+	if err := cfg.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	r := &identifierResolver{
+		cfg:    cfg,
+		walker: fieldWalker{tags: cfg.Tags, opts: cfg.Options},
+		allow:  splitPaths(cfg.Allow),
+		deny:   splitPaths(cfg.Deny),
+	}
+	for from, to := range cfg.Renames {
+		r.renames = append(r.renames, rename{from: strings.Split(from, "."), to: strings.Split(to, ".")})
+	}
+	return r.getIdentifier, nil
+}
+
+func splitPaths(paths []string) [][]string {
+	out := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		out = append(out, strings.Split(path, "."))
+	}
+	return out
+}
+
+type rename struct {
+	from []string
+	to   []string
+}
+
+type identifierResolver struct {
+	cfg     IdentifierConfig
+	walker  fieldWalker
+	allow   [][]string
+	deny    [][]string
+	renames []rename
+}
+
+func (r *identifierResolver) getIdentifier(selector []string) (any, error) {
+	if len(selector) == 0 {
+		return nil, trace.BadParameter("missing selector")
+	}
+	path := r.rename(selector)
+	// Renamed selectors are aliases defined by the config, they are
+	// checked as written in addition to the paths they resolve to.
+	renamed := len(selector) != len(path) || !hasPathPrefix(selector, path)
+	if renamed && deniedText(r.deny, selector) {
+		return nil, trace.AccessDenied("access to %v is not allowed", strings.Join(selector, "."))
+	}
+	val, ok, err := r.resolve(path, renamed && r.allowedText(selector))
+	if !ok {
+		return nil, trace.AccessDenied("access to %v is not allowed", strings.Join(selector, "."))
+	}
+	if err != nil {
+		if isNotFound(err) {
+			return nil, trace.NotFound(err.Error())
+		}
+		return nil, trace.Wrap(err)
+	}
+	if !val.IsValid() || !val.CanInterface() {
+		return nil, trace.NotFound("%v is not found", strings.Join(selector, "."))
+	}
+	return val.Interface(), nil
+}
+
+// resolve looks up the path segment by segment. Each segment is matched
+// against Allow and Deny by its canonical name, see fieldWalker.step,
+// before it is read, so fields and getters below paths that are not
+// allowed are never read. Segments after the first one that could not
+// be resolved are matched as written. It returns false if the path is
+// not allowed, allowed is true if the path is allowed regardless of Allow.
+func (r *identifierResolver) resolve(path []string, allowed bool) (reflect.Value, bool, error) {
+	m := pathMatcher{allow: r.allow, deny: r.deny, allowed: allowed || len(r.allow) == 0}
+	var val reflect.Value
+	var err error
+	if root, ok := r.cfg.Roots[path[0]]; ok {
+		val = reflect.ValueOf(root)
+	} else {
+		err = trace.NotFound("%v is not defined", path[0])
+	}
+	if !m.next(sameText(path[0])) {
+		return reflect.Value{}, false, nil
+	}
+	for i := 1; i < len(path); i++ {
+		if err != nil {
+			if !m.next(sameText(path[i])) {
+				return reflect.Value{}, false, nil
+			}
+			continue
+		}
+		next, name, getter, findErr := r.walker.find(val, path[i:])
+		if findErr != nil {
+			err = findErr
+			if !m.next(sameText(path[i])) {
+				return reflect.Value{}, false, nil
+			}
+			continue
+		}
+		if !m.next(r.sameField(val, name)) {
+			return reflect.Value{}, false, nil
+		}
+		if getter.IsValid() {
+			if next, err = callMethod("Get"+name, getter); err != nil {
+				err = trace.Wrap(err)
+			}
+		}
+		val = next
+	}
+	if !m.done() {
+		return reflect.Value{}, false, nil
+	}
+	return val, true, err
+}
+
+// sameText returns a function matching pattern segments
+// against the path segment as written.
+func sameText(name string) func(segment string) bool {
+	return func(segment string) bool {
+		return segment == name
+	}
+}
+
+// sameField returns a function matching pattern segments against the
+// field of the parent with the canonical name. Pattern segments are
+// resolved once, without reading the fields they name.
+func (r *identifierResolver) sameField(parent reflect.Value, name string) func(segment string) bool {
+	names := make(map[string]bool)
+	return func(segment string) bool {
+		same, ok := names[segment]
+		if !ok {
+			_, canonical, _, err := r.walker.find(parent, []string{segment})
+			same = err == nil && canonical == name
+			names[segment] = same
+		}
+		return same
+	}
+}
+
+// pathMatcher matches a path against Allow and Deny patterns
+// one segment at a time.
+type pathMatcher struct {
+	// allow and deny hold the patterns matching all segments seen so far.
+	allow [][]string
+	deny  [][]string
+	// allowed is set once the path is at or below an allowed path.
+	allowed bool
+	// n is the number of segments seen so far.
+	n int
+}
+
+// next matches the next path segment, it returns false once the path
+// is at or below a denied path, or can no longer be allowed.
+func (m *pathMatcher) next(same func(segment string) bool) bool {
+	i := m.n
+	m.n++
+	m.deny = matchSegment(m.deny, i, same)
+	for _, pattern := range m.deny {
+		if len(pattern) == i+1 {
+			return false
+		}
+	}
+	if m.allowed {
+		return true
+	}
+	m.allow = matchSegment(m.allow, i, same)
+	for _, pattern := range m.allow {
+		if len(pattern) == i+1 {
+			m.allowed = true
+		}
+	}
+	return m.allowed || len(m.allow) != 0
+}
+
+// done returns true if the path is allowed. Paths above denied paths
+// are not allowed, as parents give access to all fields below them.
+func (m *pathMatcher) done() bool {
+	return m.allowed && len(m.deny) == 0
+}
+
+// matchSegment returns the patterns with segment i matching the path.
+func matchSegment(patterns [][]string, i int, same func(segment string) bool) [][]string {
+	var out [][]string
+	for _, pattern := range patterns {
+		if len(pattern) > i && (pattern[i] == "*" || same(pattern[i])) {
+			out = append(out, pattern)
+		}
+	}
+	return out
+}
+
+func (r *identifierResolver) allowedText(selector []string) bool {
+	if len(r.allow) == 0 {
+		return true
+	}
+	for _, pattern := range r.allow {
+		if matchPathPrefix(pattern, selector) {
+			return true
+		}
+	}
+	return false
+}
+
+// rename replaces the longest renamed prefix of the selector.
+func (r *identifierResolver) rename(selector []string) []string {
+	var best *rename
+	for i := range r.renames {
+		rn := &r.renames[i]
+		if !hasPathPrefix(selector, rn.from) {
+			continue
+		}
+		if best == nil || len(rn.from) > len(best.from) {
+			best = rn
+		}
+	}
+	if best == nil {
+		return selector
+	}
+	return append(append([]string{}, best.to...), selector[len(best.from):]...)
+}
+
+// deniedText returns true if the selector as written is at, below or
+// above any of the denied paths.
+func deniedText(deny [][]string, selector []string) bool {
+	for _, pattern := range deny {
+		n := minInt(len(pattern), len(selector))
+		if matchPathPrefix(pattern[:n], selector) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPathPrefix returns true if the pattern matches a prefix of the path.
+// Only the pattern may contain "*" segments, they match any single segment.
+func matchPathPrefix(pattern, path []string) bool {
+	if len(pattern) > len(path) {
+		return false
+	}
+	for i := range pattern {
+		if pattern[i] != "*" && pattern[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func hasPathPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,378 @@
+package predicate
+
+import (
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+type testAccount struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		Traits       map[string][]string `json:"traits"`
+		PasswordHash string              `json:"password_hash"`
+	} `json:"spec"`
+}
+
+func TestNewGetIdentifier(t *testing.T) {
+	t.Parallel()
+
+	user := testAccount{}
+	user.Metadata.Name = "alice"
+	user.Spec.Traits = map[string][]string{"logins": {"root"}}
+	user.Spec.PasswordHash = "secret"
+
+	getID, err := NewGetIdentifier(IdentifierConfig{
+		Roots:   map[string]any{"user": user, "resource": map[string]string{"env": "prod"}},
+		Options: FieldOptions{Maps: true},
+		Allow:   []string{"user.metadata", "user.spec.*", "resource"},
+		Deny:    []string{"user.spec.password_hash"},
+		Renames: map[string]string{"user.name": "user.metadata.name"},
+	})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		desc        string
+		selector    []string
+		expect      any
+		expectError func(error) bool
+	}{
+		{
+			desc:     "allowed field",
+			selector: []string{"user", "metadata", "name"},
+			expect:   "alice",
+		},
+		{
+			desc:     "allowed by wildcard",
+			selector: []string{"user", "spec", "traits", "logins"},
+			expect:   []string{"root"},
+		},
+		{
+			desc:     "renamed field",
+			selector: []string{"user", "name"},
+			expect:   "alice",
+		},
+		{
+			desc:     "root value",
+			selector: []string{"resource"},
+			expect:   map[string]string{"env": "prod"},
+		},
+		{
+			desc:        "denied field",
+			selector:    []string{"user", "spec", "password_hash"},
+			expectError: trace.IsAccessDenied,
+		},
+		{
+			desc:        "parent of denied field",
+			selector:    []string{"user", "spec"},
+			expectError: trace.IsAccessDenied,
+		},
+		{
+			desc:        "root not in allow list",
+			selector:    []string{"user"},
+			expectError: trace.IsAccessDenied,
+		},
+		{
+			desc:        "unknown root",
+			selector:    []string{"request", "ip"},
+			expectError: trace.IsAccessDenied,
+		},
+		{
+			desc:        "missing field",
+			selector:    []string{"user", "metadata", "namespace"},
+			expectError: trace.IsNotFound,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			out, err := getID(tc.selector)
+			if tc.expectError != nil {
+				require.True(t, tc.expectError(err), "unexpected error %v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, out)
+		})
+	}
+}
+
+type testProtoAccount struct {
+	Spec *testProtoAccountSpec `protobuf:"bytes,1,opt,name=spec,proto3" json:"spec"`
+}
+
+type testProtoAccountSpec struct {
+	Login        string `protobuf:"bytes,1,opt,name=login,proto3" json:"login"`
+	PasswordHash string `protobuf:"bytes,2,opt,name=password_hash,json=passwordHash,proto3" json:"password_hash"`
+}
+
+type testGetterAccount struct {
+	spec testGetterSpec
+}
+
+func (a *testGetterAccount) GetSpec() *testGetterSpec {
+	return &a.spec
+}
+
+type testGetterSpec struct {
+	login        string
+	passwordHash string
+}
+
+func (s *testGetterSpec) GetLogin() string {
+	return s.login
+}
+
+func (s *testGetterSpec) GetPasswordHash() string {
+	return s.passwordHash
+}
+
+// TestNewGetIdentifierAliases checks that Allow and Deny apply to every
+// spelling that resolves to a field.
+func TestNewGetIdentifierAliases(t *testing.T) {
+	t.Parallel()
+
+	user := testAccount{}
+	user.Metadata.Name = "alice"
+	user.Spec.PasswordHash = "secret"
+	proto := &testProtoAccount{Spec: &testProtoAccountSpec{Login: "alice", PasswordHash: "secret"}}
+	getter := &testGetterAccount{spec: testGetterSpec{login: "alice", passwordHash: "secret"}}
+
+	for _, tc := range []struct {
+		desc     string
+		cfg      IdentifierConfig
+		allowed  [][]string
+		denied   [][]string
+		notFound [][]string
+	}{
+		{
+			desc: "json and field names",
+			cfg: IdentifierConfig{
+				Roots: map[string]any{"user": user},
+				Tags:  []string{"json", FieldNameTag},
+				Deny:  []string{"user.spec.password_hash"},
+			},
+			allowed: [][]string{
+				{"user", "metadata", "name"},
+				{"user", "Metadata", "Name"},
+			},
+			denied: [][]string{
+				{"user", "spec", "password_hash"},
+				{"user", "spec", "PasswordHash"},
+				{"user", "Spec", "password_hash"},
+				{"user", "spec", "passwordHash"},
+				{"user", "Spec"},
+				{"user"},
+			},
+		},
+		{
+			desc: "deny pattern with field names",
+			cfg: IdentifierConfig{
+				Roots: map[string]any{"user": user},
+				Tags:  []string{"json", FieldNameTag},
+				Allow: []string{"user.Metadata", "user.Spec.Traits"},
+				Deny:  []string{"user.Spec.PasswordHash"},
+			},
+			allowed: [][]string{
+				{"user", "metadata", "name"},
+			},
+			denied: [][]string{
+				{"user", "spec", "password_hash"},
+				{"user", "spec"},
+			},
+		},
+		{
+			desc: "protobuf names",
+			cfg: IdentifierConfig{
+				Roots: map[string]any{"user": proto},
+				Tags:  []string{ProtobufTag},
+				Deny:  []string{"user.spec.password_hash"},
+			},
+			allowed: [][]string{
+				{"user", "spec", "login"},
+			},
+			denied: [][]string{
+				{"user", "spec", "password_hash"},
+				{"user", "spec", "passwordHash"},
+			},
+		},
+		{
+			desc: "getters",
+			cfg: IdentifierConfig{
+				Roots:   map[string]any{"user": getter},
+				Options: FieldOptions{Getters: true},
+				Deny:    []string{"user.spec.password_hash"},
+			},
+			allowed: [][]string{
+				{"user", "spec", "login"},
+			},
+			denied: [][]string{
+				{"user", "spec", "password_hash"},
+				{"user", "spec", "passwordHash"},
+				{"user", "Spec", "PasswordHash"},
+			},
+		},
+		{
+			desc: "wildcard in selector",
+			cfg: IdentifierConfig{
+				Roots:   map[string]any{"resource": map[string]string{"env": "prod", "*": "all"}},
+				Options: FieldOptions{Maps: true},
+				Allow:   []string{"resource.env"},
+			},
+			allowed: [][]string{
+				{"resource", "env"},
+			},
+			denied: [][]string{
+				{"resource", "*"},
+			},
+		},
+		{
+			desc: "wildcard in pattern",
+			cfg: IdentifierConfig{
+				Roots:   map[string]any{"resource": map[string]string{"env": "prod"}},
+				Options: FieldOptions{Maps: true},
+				Deny:    []string{"resource.*"},
+			},
+			denied: [][]string{
+				{"resource", "env"},
+				{"resource"},
+			},
+			notFound: [][]string{
+				{"request"},
+			},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			getID, err := NewGetIdentifier(tc.cfg)
+			require.NoError(t, err)
+			for _, selector := range tc.allowed {
+				_, err := getID(selector)
+				require.NoError(t, err, "selector %v", selector)
+			}
+			for _, selector := range tc.denied {
+				out, err := getID(selector)
+				require.True(t, trace.IsAccessDenied(err), "selector %v: unexpected error %v", selector, err)
+				require.Nil(t, out)
+			}
+			for _, selector := range tc.notFound {
+				_, err := getID(selector)
+				require.True(t, trace.IsNotFound(err), "selector %v: unexpected error %v", selector, err)
+			}
+		})
+	}
+}
+
+type testCountingAccount struct {
+	spec testCountingSpec
+	// specCalls counts calls to GetSpec.
+	specCalls int
+}
+
+func (a *testCountingAccount) GetSpec() *testCountingSpec {
+	a.specCalls++
+	return &a.spec
+}
+
+type testCountingSpec struct {
+	login string
+	// passwordHashCalls counts calls to GetPasswordHash.
+	passwordHashCalls int
+}
+
+func (s *testCountingSpec) GetLogin() string {
+	return s.login
+}
+
+func (s *testCountingSpec) GetPasswordHash() string {
+	s.passwordHashCalls++
+	return "secret"
+}
+
+// TestNewGetIdentifierGetterCalls checks that getters of paths
+// that are not allowed are never called.
+func TestNewGetIdentifierGetterCalls(t *testing.T) {
+	t.Parallel()
+
+	user := &testCountingAccount{spec: testCountingSpec{login: "alice"}}
+	resource := &testCountingAccount{}
+	getID, err := NewGetIdentifier(IdentifierConfig{
+		Roots:   map[string]any{"user": user, "resource": resource},
+		Options: FieldOptions{Getters: true},
+		Allow:   []string{"user"},
+		Deny:    []string{"user.spec.password_hash"},
+	})
+	require.NoError(t, err)
+
+	out, err := getID([]string{"user", "spec", "login"})
+	require.NoError(t, err)
+	require.Equal(t, "alice", out)
+
+	for _, selector := range [][]string{
+		{"user", "spec", "password_hash"},
+		{"user", "spec", "PasswordHash"},
+		{"user", "Spec", "passwordHash", "length"},
+	} {
+		_, err := getID(selector)
+		require.True(t, trace.IsAccessDenied(err), "selector %v: unexpected error %v", selector, err)
+	}
+	require.Equal(t, 0, user.spec.passwordHashCalls)
+
+	_, err = getID([]string{"resource", "spec", "login"})
+	require.True(t, trace.IsAccessDenied(err), "unexpected error %v", err)
+	require.Equal(t, 0, resource.specCalls)
+}
+
+func TestNewGetIdentifierParse(t *testing.T) {
+	t.Parallel()
+
+	user := testAccount{}
+	user.Spec.Traits = map[string][]string{"logins": {"root"}}
+
+	getID, err := NewGetIdentifier(IdentifierConfig{
+		Roots: map[string]any{"user": user},
+		Deny:  []string{"user.spec.password_hash"},
+	})
+	require.NoError(t, err)
+
+	p, err := NewParser(Def{
+		Functions:     map[string]any{"contains": Contains},
+		GetIdentifier: getID,
+		GetProperty:   GetStringMapValue,
+	})
+	require.NoError(t, err)
+
+	out, err := p.Parse(`contains(user.spec.traits["logins"], "root")`)
+	require.NoError(t, err)
+	require.True(t, out.(BoolPredicate)())
+
+	_, err = p.Parse(`contains(user.spec.password_hash, "a")`)
+	require.True(t, trace.IsAccessDenied(err), "unexpected error %v", err)
+}
+
+func TestIdentifierConfig(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc string
+		cfg  IdentifierConfig
+	}{
+		{
+			desc: "missing roots",
+			cfg:  IdentifierConfig{},
+		},
+		{
+			desc: "empty path segment",
+			cfg:  IdentifierConfig{Roots: map[string]any{"user": nil}, Deny: []string{"user..name"}},
+		},
+		{
+			desc: "rename to unknown root",
+			cfg:  IdentifierConfig{Roots: map[string]any{"user": nil}, Renames: map[string]string{"name": "account.name"}},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := NewGetIdentifier(tc.cfg)
+			require.True(t, trace.IsBadParameter(err), "unexpected error %v", err)
+		})
+	}
+}
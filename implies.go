@@ -0,0 +1,103 @@
+package predicate
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+
+	"github.com/gravitational/trace"
+)
+
+// ProofResult is the result of Implies and Equivalent.
+type ProofResult struct {
+	// Status is TriTrue if the property holds, TriFalse if it does not, and
+	// TriUnknown if the solver could not decide, see Satisfiable for the
+	// atoms the solver understands.
+	Status Tri
+	// Counterexample is set if the property does not hold. It holds values
+	// of identifiers for which the first rule is true and the second is
+	// false, or the other way around if Reversed is set.
+	Counterexample Model
+	// Reversed is set if the counterexample of Equivalent makes the second
+	// rule true and the first false.
+	Reversed bool
+}
+
+// Implies checks if rule a implies rule b, i.e. b is true whenever a is true,
+// so a is at most as permissive as b, e.g. env == "prod" && admin implies
+// env == "prod". It uses default SatOptions.
+func (d Def) Implies(a, b string) (ProofResult, error) {
+	return d.ImpliesWithOptions(a, b, SatOptions{})
+}
+
+// ImpliesWithOptions checks if rule a implies rule b using the solver options.
+func (d Def) ImpliesWithOptions(a, b string, opts SatOptions) (ProofResult, error) {
+	ax, bx, err := parsePair(a, b)
+	if err != nil {
+		return ProofResult{}, trace.Wrap(err)
+	}
+	return d.implies(ax, bx, opts)
+}
+
+// Equivalent checks if rules a and b are true for the same values
+// of identifiers. It uses default SatOptions.
+func (d Def) Equivalent(a, b string) (ProofResult, error) {
+	return d.EquivalentWithOptions(a, b, SatOptions{})
+}
+
+// EquivalentWithOptions checks if rules a and b are equivalent using
+// the solver options.
+func (d Def) EquivalentWithOptions(a, b string, opts SatOptions) (ProofResult, error) {
+	ax, bx, err := parsePair(a, b)
+	if err != nil {
+		return ProofResult{}, trace.Wrap(err)
+	}
+	forward, err := d.implies(ax, bx, opts)
+	if err != nil {
+		return ProofResult{}, trace.Wrap(err)
+	}
+	if forward.Status == TriFalse {
+		return forward, nil
+	}
+	backward, err := d.implies(bx, ax, opts)
+	if err != nil {
+		return ProofResult{}, trace.Wrap(err)
+	}
+	if backward.Status == TriFalse {
+		backward.Reversed = true
+		return backward, nil
+	}
+	if forward.Status == TriUnknown || backward.Status == TriUnknown {
+		return ProofResult{Status: TriUnknown}, nil
+	}
+	return ProofResult{Status: TriTrue}, nil
+}
+
+// implies checks a implies b by looking for values satisfying a && !b.
+func (d Def) implies(a, b ast.Expr, opts SatOptions) (ProofResult, error) {
+	expr := &ast.BinaryExpr{X: a, Op: token.LAND, Y: &ast.UnaryExpr{Op: token.NOT, X: b}}
+	result, err := d.SatisfiableExpr(expr, opts)
+	if err != nil {
+		return ProofResult{}, trace.Wrap(err)
+	}
+	switch result.Status {
+	case Unsatisfiable:
+		return ProofResult{Status: TriTrue}, nil
+	case Satisfiable:
+		return ProofResult{Status: TriFalse, Counterexample: result.Model}, nil
+	default:
+		return ProofResult{Status: TriUnknown}, nil
+	}
+}
+
+func parsePair(a, b string) (ast.Expr, ast.Expr, error) {
+	ax, err := parser.ParseExpr(a)
+	if err != nil {
+		return nil, nil, trace.BadParameter("invalid first rule: %v", err)
+	}
+	bx, err := parser.ParseExpr(b)
+	if err != nil {
+		return nil, nil, trace.BadParameter("invalid second rule: %v", err)
+	}
+	return ax, bx, nil
+}
@@ -0,0 +1,79 @@
+package predicate
+
+import (
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImplies(t *testing.T) {
+	t.Parallel()
+
+	d := Def{}
+	tests := []struct {
+		a, b string
+		want Tri
+	}{
+		{a: `env == "prod" && admin`, b: `env == "prod"`, want: TriTrue},
+		{a: `env == "prod"`, b: `env == "prod" && admin`, want: TriFalse},
+		{a: `age > 10`, b: `age > 5`, want: TriTrue},
+		{a: `age > 5`, b: `age > 10`, want: TriFalse},
+		{a: `contains(roles, "a") && contains(roles, "b")`, b: `contains(roles, "a") || env == "dev"`, want: TriTrue},
+		{a: `env == "prod"`, b: `env != "dev"`, want: TriTrue},
+		{a: `false`, b: `anything`, want: TriTrue},
+		{a: `lower(name) == "a"`, b: `env == "prod"`, want: TriUnknown},
+		{a: `lower(name) == "a" && env == "prod"`, b: `env == "prod"`, want: TriTrue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" => "+tt.b, func(t *testing.T) {
+			result, err := d.Implies(tt.a, tt.b)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, result.Status, "got %v", result.Status)
+			if tt.want == TriFalse {
+				require.NotNil(t, result.Counterexample)
+			} else {
+				require.Nil(t, result.Counterexample)
+			}
+		})
+	}
+
+	result, err := d.Implies(`age > 5`, `age > 10`)
+	require.NoError(t, err)
+	require.Equal(t, Model{"age": 6}, result.Counterexample)
+	require.False(t, result.Reversed)
+
+	_, err = d.Implies(`age >`, `age > 10`)
+	require.True(t, trace.IsBadParameter(err), "unexpected error %v", err)
+}
+
+func TestEquivalent(t *testing.T) {
+	t.Parallel()
+
+	d := Def{Inverses: map[string]string{"<": ">="}}
+	tests := []struct {
+		a, b string
+		want Tri
+	}{
+		{a: `a && (b || c)`, b: `a && b || a && c`, want: TriTrue},
+		{a: `!(x < 1 || y)`, b: `x >= 1 && !y`, want: TriTrue},
+		{a: `env == "prod" || env == "prod"`, b: `env == "prod"`, want: TriTrue},
+		{a: `age >= 10 && age <= 10`, b: `age == 10`, want: TriTrue},
+		{a: `env == "prod"`, b: `env == "prod" || admin`, want: TriFalse},
+		{a: `f(x)`, b: `f(x) && f(x)`, want: TriTrue},
+		{a: `f(x)`, b: `g(x)`, want: TriUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" <=> "+tt.b, func(t *testing.T) {
+			result, err := d.Equivalent(tt.a, tt.b)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, result.Status, "got %v", result.Status)
+		})
+	}
+
+	// The second rule is more permissive.
+	result, err := d.Equivalent(`env == "prod"`, `env == "prod" || admin`)
+	require.NoError(t, err)
+	require.True(t, result.Reversed)
+	require.Equal(t, true, result.Counterexample["admin"])
+}
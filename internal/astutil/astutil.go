@@ -0,0 +1,86 @@
+// Package astutil holds helpers for expressions parsed by go/parser
+// shared by predicate and its subpackages.
+package astutil
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// Unparen removes parentheses around the expression.
+func Unparen(expr ast.Expr) ast.Expr {
+	for {
+		paren, ok := expr.(*ast.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = paren.X
+	}
+}
+
+// LogicalOperands returns operands of the chain of op operators,
+// e.g. a, b and c for a && (b && c).
+func LogicalOperands(op token.Token, expr ast.Expr) []ast.Expr {
+	bin, ok := Unparen(expr).(*ast.BinaryExpr)
+	if !ok || bin.Op != op {
+		return []ast.Expr{expr}
+	}
+	return append(LogicalOperands(op, bin.X), LogicalOperands(op, bin.Y)...)
+}
+
+// SelectorOf returns the identifier path of identifiers and selectors,
+// e.g. user, spec and name for user.spec.name, and false for other
+// expressions.
+func SelectorOf(expr ast.Expr) ([]string, bool) {
+	return selectorOf(Unparen(expr))
+}
+
+// selectorOf returns the identifier path, parentheses are
+// only allowed around the whole selector.
+func selectorOf(expr ast.Expr) ([]string, bool) {
+	switch n := expr.(type) {
+	case *ast.Ident:
+		return []string{n.Name}, true
+	case *ast.SelectorExpr:
+		fields, ok := selectorOf(n.X)
+		if !ok {
+			return nil, false
+		}
+		return append(fields, n.Sel.Name), true
+	default:
+		return nil, false
+	}
+}
+
+// Position is a position in the expression text.
+type Position struct {
+	// Offset is the byte offset, starting at 0.
+	Offset int
+	// Line is the line number, starting at 1.
+	Line int
+	// Column is the byte offset in the line, starting at 1.
+	Column int
+}
+
+// PositionOf converts a position of a node returned by parser.ParseExpr
+// to the position in the expression text.
+func PositionOf(in string, pos token.Pos) Position {
+	// parser.ParseExpr parses the expression as a single file with base 1.
+	offset := int(pos) - 1
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(in) {
+		offset = len(in)
+	}
+	p := Position{Offset: offset, Line: 1, Column: 1}
+	for i := 0; i < offset; i++ {
+		if in[i] == '\n' {
+			p.Line++
+			p.Column = 1
+		} else {
+			p.Column++
+		}
+	}
+	return p
+}
@@ -0,0 +1,89 @@
+package astutil
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// PrimaryPrec is the precedence of identifiers, literals, selectors, calls and
+// index expressions, higher than any operator.
+const PrimaryPrec = token.UnaryPrec + 1
+
+// ExprString returns the text of the expression with single spaces around
+// binary operators and with parentheses only where operator precedence
+// requires them, e.g. "a || b && c" and "(a || b) && c".
+func ExprString(expr ast.Expr) string {
+	var sb strings.Builder
+	writeExpr(&sb, expr)
+	return sb.String()
+}
+
+// Precedence returns the precedence of the expression's outermost operator.
+func Precedence(expr ast.Expr) int {
+	switch n := Unparen(expr).(type) {
+	case *ast.BinaryExpr:
+		return n.Op.Precedence()
+	case *ast.UnaryExpr:
+		return token.UnaryPrec
+	default:
+		return PrimaryPrec
+	}
+}
+
+// writeOperand writes the expression, in parentheses if its precedence
+// is lower than minPrec.
+func writeOperand(sb *strings.Builder, expr ast.Expr, minPrec int) {
+	if Precedence(expr) < minPrec {
+		sb.WriteString("(")
+		writeExpr(sb, expr)
+		sb.WriteString(")")
+		return
+	}
+	writeExpr(sb, expr)
+}
+
+func writeExpr(sb *strings.Builder, expr ast.Expr) {
+	switch n := expr.(type) {
+	case *ast.ParenExpr:
+		writeExpr(sb, n.X)
+	case *ast.BinaryExpr:
+		prec := n.Op.Precedence()
+		writeOperand(sb, n.X, prec)
+		sb.WriteString(" ")
+		sb.WriteString(n.Op.String())
+		sb.WriteString(" ")
+		// Operators are left associative, so a right operand with the same
+		// precedence needs parentheses to keep the tree, e.g. a && (b && c).
+		writeOperand(sb, n.Y, prec+1)
+	case *ast.UnaryExpr:
+		sb.WriteString(n.Op.String())
+		writeOperand(sb, n.X, token.UnaryPrec)
+	case *ast.BasicLit:
+		sb.WriteString(n.Value)
+	case *ast.Ident:
+		sb.WriteString(n.Name)
+	case *ast.SelectorExpr:
+		writeOperand(sb, n.X, PrimaryPrec)
+		sb.WriteString(".")
+		sb.WriteString(n.Sel.Name)
+	case *ast.IndexExpr:
+		writeOperand(sb, n.X, PrimaryPrec)
+		sb.WriteString("[")
+		writeExpr(sb, n.Index)
+		sb.WriteString("]")
+	case *ast.CallExpr:
+		writeOperand(sb, n.Fun, PrimaryPrec)
+		sb.WriteString("(")
+		for i, arg := range n.Args {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			writeExpr(sb, arg)
+		}
+		sb.WriteString(")")
+	default:
+		sb.WriteString(types.ExprString(expr))
+	}
+}
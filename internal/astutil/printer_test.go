@@ -0,0 +1,33 @@
+package astutil
+
+import (
+	"go/parser"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExprString(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		input  string
+		expect string
+	}{
+		{input: `a||b&&c`, expect: `a || b && c`},
+		{input: `(a||b)&&c`, expect: `(a || b) && c`},
+		{input: `((a))`, expect: `a`},
+		{input: `a && (b && c)`, expect: `a && (b && c)`},
+		{input: `(a && b) && c`, expect: `a && b && c`},
+		{input: `!(a && b)`, expect: `!(a && b)`},
+		{input: `!!a.b`, expect: `!!a.b`},
+		{input: `f( (a) , b[ "k" ] )`, expect: `f(a, b["k"])`},
+		{input: `(a == b) == (c < d)`, expect: `a == b == (c < d)`},
+		{input: "x == `raw`", expect: "x == `raw`"},
+		{input: `set("a").contains((b))`, expect: `set("a").contains(b)`},
+	} {
+		expr, err := parser.ParseExpr(tc.input)
+		require.NoError(t, err)
+		require.Equal(t, tc.expect, ExprString(expr))
+	}
+}
@@ -0,0 +1,337 @@
+package predicate
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gravitational/trace"
+)
+
+// GetJSONIdentifier returns GetIdentifierFn that resolves selectors in
+// values decoded by encoding/json, e.g. user.traits.logins resolves
+// doc["user"]["traits"]["logins"], and numeric segments index arrays.
+// Missing keys fail with trace.NotFound. Results are normalized as
+// described in NormalizeJSON.
+func GetJSONIdentifier(doc map[string]any) GetIdentifierFn {
+	return func(selector []string) (any, error) {
+		var val any = doc
+		for i, segment := range selector {
+			next, err := jsonChild(val, segment)
+			if err != nil {
+				return nil, trace.NotFound("%v is not found: %v", strings.Join(selector[:i+1], "."), err)
+			}
+			val = next
+		}
+		return NormalizeJSON(val), nil
+	}
+}
+
+// GetJSONProperty is GetPropertyFn for values decoded by encoding/json,
+// objects are indexed by strings, e.g. labels["env"], and arrays by numbers,
+// e.g. items[0]. Missing keys fail with trace.NotFound.
+func GetJSONProperty(mapVal, keyVal any) (any, error) {
+	switch key := keyVal.(type) {
+	case string:
+		val, err := jsonChild(mapVal, key)
+		if err != nil {
+			return nil, trace.NotFound("%q is not found: %v", key, err)
+		}
+		return NormalizeJSON(val), nil
+	default:
+		index, ok := jsonIndex(keyVal)
+		if !ok {
+			return nil, trace.BadParameter("unsupported key type %T", keyVal)
+		}
+		val, err := jsonChild(mapVal, strconv.Itoa(index))
+		if err != nil {
+			return nil, trace.NotFound("%v is not found: %v", index, err)
+		}
+		return NormalizeJSON(val), nil
+	}
+}
+
+// jsonIndex converts integer numbers of any representation to an index.
+func jsonIndex(v any) (int, bool) {
+	switch n := NormalizeJSON(v).(type) {
+	case int:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// jsonChild returns the object member or the array element.
+func jsonChild(val any, segment string) (any, error) {
+	switch v := val.(type) {
+	case map[string]any:
+		child, ok := v[segment]
+		if !ok {
+			return nil, trace.NotFound("missing key %q", segment)
+		}
+		return child, nil
+	case []any:
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 || index >= len(v) {
+			return nil, trace.NotFound("missing index %q", segment)
+		}
+		return v[index], nil
+	case []string:
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 || index >= len(v) {
+			return nil, trace.NotFound("missing index %q", segment)
+		}
+		return v[index], nil
+	default:
+		return nil, trace.NotFound("%T has no key %q", val, segment)
+	}
+}
+
+// NormalizeJSON converts values decoded by encoding/json to values that
+// work with the functions and operators of this package:
+//
+//   - float64 and json.Number values that are integers are converted to int,
+//     so they compare equal to integer literals, other numbers to float64
+//   - arrays of strings are converted to []string, other arrays are
+//     normalized element by element
+//
+// Objects are returned as is.
+func NormalizeJSON(v any) any {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil && int64(int(i)) == i {
+			return int(i)
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return val.String()
+		}
+		return normalizeFloat(f)
+	case float64:
+		return normalizeFloat(val)
+	case []any:
+		strs := make([]string, 0, len(val))
+		for _, elem := range val {
+			s, ok := elem.(string)
+			if !ok {
+				break
+			}
+			strs = append(strs, s)
+		}
+		if len(strs) == len(val) && len(val) != 0 {
+			return strs
+		}
+		out := make([]any, len(val))
+		for i, elem := range val {
+			out[i] = NormalizeJSON(elem)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func normalizeFloat(f float64) any {
+	if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 && int64(int(f)) == int64(f) {
+		return int(f)
+	}
+	return f
+}
+
+// JSONModule returns functions for values decoded by encoding/json:
+//
+//	jsonPointer(doc, pointer) - returns the value at the RFC 6901 pointer,
+//	                            e.g. jsonPointer(doc, "/items/0/name")
+//	jsonPath(doc, path)       - returns the list of values matching the
+//	                            path, e.g. jsonPath(doc, "$.items[*].name")
+//
+// jsonPath supports the root $, members .name and ['name'], indexes [0],
+// and wildcards .* and [*]. Results are normalized as described in
+// NormalizeJSON.
+func JSONModule() Module {
+	return Module{
+		Functions: map[string]any{
+			"jsonPointer": jsonPointer,
+			"jsonPath":    jsonPath,
+		},
+		FunctionInfo: map[string]FunctionInfo{
+			"jsonPointer": {Literals: []LiteralFunc{nil, parseJSONPointerLiteral}, Cost: jsonPointerCost},
+			"jsonPath":    {Literals: []LiteralFunc{nil, parseJSONPathLiteral}, Cost: jsonPathCost},
+		},
+	}
+}
+
+var (
+	// jsonPointerCost is linear in the pointer, it has at most
+	// a reference token per byte.
+	jsonPointerCost = Linear(1, 1, 1)
+	// jsonPathCost is also linear in the document, wildcards
+	// visit every member or element.
+	jsonPathCost = linearSum(1, 1, 0, 1)
+)
+
+// jsonPointerTokens are reference tokens of a parsed JSON pointer.
+type jsonPointerTokens []string
+
+func parseJSONPointerLiteral(v any) (any, error) {
+	return toJSONPointer(v)
+}
+
+func toJSONPointer(v any) (jsonPointerTokens, error) {
+	switch p := v.(type) {
+	case jsonPointerTokens:
+		return p, nil
+	case string:
+		return parseJSONPointer(p)
+	default:
+		return nil, trace.BadParameter("expected JSON pointer to be a string, got %T", v)
+	}
+}
+
+func parseJSONPointer(pointer string) (jsonPointerTokens, error) {
+	if pointer == "" {
+		return jsonPointerTokens{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, trace.BadParameter("invalid JSON pointer %q: must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, trace.BadParameter("invalid JSON pointer %q: invalid escape", pointer)
+			}
+		}
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func jsonPointer(doc any, pointer any) (any, error) {
+	tokens, err := toJSONPointer(pointer)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	val := doc
+	for _, token := range tokens {
+		val, err = jsonChild(val, token)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return NormalizeJSON(val), nil
+}
+
+// jsonPathStep is a step of a parsed JSON path, wildcard steps
+// match all members or elements.
+type jsonPathStep struct {
+	key      string
+	wildcard bool
+}
+
+// jsonPathSteps are steps of a parsed JSON path.
+type jsonPathSteps []jsonPathStep
+
+func parseJSONPathLiteral(v any) (any, error) {
+	return toJSONPath(v)
+}
+
+func toJSONPath(v any) (jsonPathSteps, error) {
+	switch p := v.(type) {
+	case jsonPathSteps:
+		return p, nil
+	case string:
+		return parseJSONPath(p)
+	default:
+		return nil, trace.BadParameter("expected JSON path to be a string, got %T", v)
+	}
+}
+
+func parseJSONPath(path string) (jsonPathSteps, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, trace.BadParameter("invalid JSON path %q: must start with $", path)
+	}
+	steps := jsonPathSteps{}
+	for i := 1; i < len(path); {
+		switch path[i] {
+		case '.':
+			end := i + 1
+			for end < len(path) && path[end] != '.' && path[end] != '[' {
+				end++
+			}
+			name := path[i+1 : end]
+			if name == "" {
+				return nil, trace.BadParameter("invalid JSON path %q: empty member name", path)
+			}
+			steps = append(steps, jsonPathStep{key: name, wildcard: name == "*"})
+			i = end
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, trace.BadParameter("invalid JSON path %q: missing ]", path)
+			}
+			inner := path[i+1 : i+end]
+			i += end + 1
+			switch {
+			case inner == "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, jsonPathStep{key: inner[1 : len(inner)-1]})
+			case isNumeric(inner):
+				steps = append(steps, jsonPathStep{key: inner})
+			default:
+				return nil, trace.BadParameter("invalid JSON path %q: unsupported selector [%v]", path, inner)
+			}
+		default:
+			return nil, trace.BadParameter("invalid JSON path %q: unexpected %q", path, path[i])
+		}
+	}
+	return steps, nil
+}
+
+func jsonPath(doc any, path any) (any, error) {
+	steps, err := toJSONPath(path)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	current := []any{doc}
+	for _, step := range steps {
+		var next []any
+		for _, val := range current {
+			if !step.wildcard {
+				if child, err := jsonChild(val, step.key); err == nil {
+					next = append(next, child)
+				}
+				continue
+			}
+			switch v := val.(type) {
+			case map[string]any:
+				for _, key := range sortedKeys(v) {
+					next = append(next, v[key])
+				}
+			case []any:
+				next = append(next, v...)
+			case []string:
+				for _, elem := range v {
+					next = append(next, elem)
+				}
+			}
+		}
+		current = next
+	}
+	if current == nil {
+		current = []any{}
+	}
+	return NormalizeJSON(current), nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,126 @@
+package predicate
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+const testJSONDoc = `{
+	"user": {
+		"name": "alice",
+		"age": 42,
+		"score": 4.5,
+		"traits": {"logins": ["root", "admin"]},
+		"groups": [{"name": "dev"}, {"name": "ops"}]
+	},
+	"a/b": {"c~d": true}
+}`
+
+func decodeTestJSON(t *testing.T, useNumber bool) map[string]any {
+	dec := json.NewDecoder(bytes.NewBufferString(testJSONDoc))
+	if useNumber {
+		dec.UseNumber()
+	}
+	var doc map[string]any
+	require.NoError(t, dec.Decode(&doc))
+	return doc
+}
+
+func TestJSON(t *testing.T) {
+	t.Parallel()
+
+	for _, useNumber := range []bool{false, true} {
+		doc := decodeTestJSON(t, useNumber)
+		p := newTestParser(t, Def{
+			Operators: Operators{
+				EQ: EqualValues,
+				GT: Greater,
+				LT: Less,
+			},
+			Functions:     map[string]any{"contains": Contains},
+			GetIdentifier: GetJSONIdentifier(doc),
+			GetProperty:   GetJSONProperty,
+		}, nil, JSONModule(), SetsModule())
+
+		for _, tc := range []struct {
+			input  string
+			expect any
+		}{
+			{input: `user.name`, expect: "alice"},
+			{input: `user.age`, expect: 42},
+			{input: `user.score`, expect: 4.5},
+			{input: `user.age == 42 && user.score > 4`, expect: true},
+			{input: `user.score == 4`, expect: false},
+			{input: `contains(user.traits.logins, "root")`, expect: true},
+			{input: `user.traits["logins"][1]`, expect: "admin"},
+			{input: `user.groups[0]["name"]`, expect: "dev"},
+			{input: `jsonPointer(user, "/groups/1/name")`, expect: "ops"},
+			{input: `jsonPointer(user, "/age") == 42`, expect: true},
+			{input: `jsonPointer(user, "")`, expect: doc["user"]},
+			{input: `jsonPath(user, "$.groups[*].name")`, expect: []string{"dev", "ops"}},
+			{input: `jsonPath(user, "$['traits'].logins[0]")`, expect: []string{"root"}},
+			{input: `jsonPath(user, "$.groups.*.missing")`, expect: []any{}},
+			{input: `jsonPath(user, "$.groups[*].name").containsAll("dev")`, expect: true},
+		} {
+			t.Run(tc.input, func(t *testing.T) {
+				require.Equal(t, tc.expect, parseAndEval(t, p, tc.input))
+			})
+		}
+
+		for _, tc := range []struct {
+			input       string
+			expectError func(error) bool
+		}{
+			{input: `user.email`, expectError: trace.IsNotFound},
+			{input: `user.groups[2]`, expectError: trace.IsNotFound},
+			{input: `user.traits["email"]`, expectError: trace.IsNotFound},
+			{input: `user.groups[1.5]`, expectError: trace.IsBadParameter},
+			{input: `jsonPointer(user, "groups")`, expectError: trace.IsBadParameter},
+			{input: `jsonPointer(user, "/groups/~2")`, expectError: trace.IsBadParameter},
+			{input: `jsonPath(user, "groups")`, expectError: trace.IsBadParameter},
+			{input: `jsonPath(user, "$.groups[?(@.name)]")`, expectError: trace.IsBadParameter},
+		} {
+			t.Run(tc.input, func(t *testing.T) {
+				_, err := p.Parse(tc.input)
+				require.True(t, tc.expectError(err), "unexpected error %v", err)
+			})
+		}
+	}
+}
+
+func TestGetJSONIdentifier(t *testing.T) {
+	t.Parallel()
+
+	getID := GetJSONIdentifier(decodeTestJSON(t, true))
+	out, err := getID([]string{"user", "groups", "1", "name"})
+	require.NoError(t, err)
+	require.Equal(t, "ops", out)
+
+	_, err = getID([]string{"user", "groups", "2", "name"})
+	require.True(t, trace.IsNotFound(err), "unexpected error %v", err)
+}
+
+func TestJSONPointerEscapes(t *testing.T) {
+	t.Parallel()
+
+	doc := decodeTestJSON(t, false)
+	out, err := jsonPointer(doc, "/a~1b/c~0d")
+	require.NoError(t, err)
+	require.Equal(t, true, out)
+}
+
+func TestNormalizeJSON(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, 3, NormalizeJSON(3.0))
+	require.Equal(t, 3.5, NormalizeJSON(3.5))
+	require.Equal(t, 7, NormalizeJSON(json.Number("7")))
+	require.Equal(t, 1e300, NormalizeJSON(json.Number("1e300")))
+	require.Equal(t, []string{"a"}, NormalizeJSON([]any{"a"}))
+	require.Equal(t, []any{1, "a"}, NormalizeJSON([]any{1.0, "a"}))
+	require.Equal(t, []any{}, NormalizeJSON([]any{}))
+}
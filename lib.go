@@ -17,10 +17,13 @@ limitations under the License.
 package predicate
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/gravitational/trace"
 )
@@ -129,15 +132,324 @@ func Not(a BoolPredicate) BoolPredicate {
 	}
 }
 
+// Tri is a value of three-valued logic, a boolean that may be unknown.
+type Tri int
+
+const (
+	// TriFalse is false.
+	TriFalse Tri = iota
+	// TriTrue is true.
+	TriTrue
+	// TriUnknown is neither true nor false, e.g. when a value is missing.
+	TriUnknown
+)
+
+// String returns true, false or unknown.
+func (t Tri) String() string {
+	switch t {
+	case TriFalse:
+		return "false"
+	case TriTrue:
+		return "true"
+	default:
+		return "unknown"
+	}
+}
+
+// TriOf converts a boolean to Tri.
+func TriOf(b bool) Tri {
+	if b {
+		return TriTrue
+	}
+	return TriFalse
+}
+
+// TriPredicate is a function without arguments that returns
+// a three-valued logic value when called.
+type TriPredicate func() Tri
+
+// AndTri is a tri-state predicate that returns the result of && operation
+// in Kleene logic: false if any of the values is false, unknown if any
+// of the values is unknown, and true otherwise.
+func AndTri(a, b TriPredicate) TriPredicate {
+	return func() Tri {
+		av := a()
+		if av == TriFalse {
+			return TriFalse
+		}
+		bv := b()
+		if bv == TriFalse {
+			return TriFalse
+		}
+		if av == TriUnknown || bv == TriUnknown {
+			return TriUnknown
+		}
+		return TriTrue
+	}
+}
+
+// OrTri is a tri-state predicate that returns the result of || operation
+// in Kleene logic: true if any of the values is true, unknown if any
+// of the values is unknown, and false otherwise.
+func OrTri(a, b TriPredicate) TriPredicate {
+	return func() Tri {
+		av := a()
+		if av == TriTrue {
+			return TriTrue
+		}
+		bv := b()
+		if bv == TriTrue {
+			return TriTrue
+		}
+		if av == TriUnknown || bv == TriUnknown {
+			return TriUnknown
+		}
+		return TriFalse
+	}
+}
+
+// NotTri is a tri-state predicate that negates the result,
+// unknown stays unknown.
+func NotTri(a TriPredicate) TriPredicate {
+	return func() Tri {
+		switch a() {
+		case TriFalse:
+			return TriTrue
+		case TriTrue:
+			return TriFalse
+		default:
+			return TriUnknown
+		}
+	}
+}
+
+// Comparable is implemented by values that can be ordered by Compare
+// and the comparison operators, e.g. semantic versions.
+type Comparable interface {
+	// Compare returns a negative number, zero or a positive number if the
+	// value is less than, equal to or greater than other.
+	Compare(other any) (int, error)
+}
+
+// Compare compares numbers of any type, strings and Comparable values,
+// returning a negative number, zero or a positive number if a is less than,
+// equal to or greater than b.
+func Compare(a, b any) (int, error) {
+	if ac, ok := a.(Comparable); ok {
+		c, err := ac.Compare(b)
+		return c, trace.Wrap(err)
+	}
+	if bc, ok := b.(Comparable); ok {
+		c, err := bc.Compare(a)
+		return -c, trace.Wrap(err)
+	}
+	if as, ok := a.(string); ok {
+		if bs, ok := b.(string); ok {
+			return strings.Compare(as, bs), nil
+		}
+		return 0, trace.BadParameter("can not compare %T and %T", a, b)
+	}
+	an, aok := toNumber(a)
+	bn, bok := toNumber(b)
+	if !aok || !bok {
+		return 0, trace.BadParameter("can not compare %T and %T", a, b)
+	}
+	return an.compare(bn), nil
+}
+
+// numberKind is the representation a number is compared in.
+type numberKind int
+
+const (
+	intNumber numberKind = iota
+	uintNumber
+	floatNumber
+)
+
+// number is a number of any type, integers are kept exact.
+type number struct {
+	kind numberKind
+	i    int64
+	u    uint64
+	f    float64
+}
+
+// toNumber converts numbers of any type, including json.Number, to number.
+func toNumber(v any) (number, bool) {
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return number{kind: intNumber, i: i}, true
+		}
+		if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+			return number{kind: uintNumber, u: u}, true
+		}
+		f, err := n.Float64()
+		return number{kind: floatNumber, f: f}, err == nil
+	}
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return number{kind: intNumber, i: val.Int()}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return number{kind: uintNumber, u: val.Uint()}, true
+	case reflect.Float32, reflect.Float64:
+		return number{kind: floatNumber, f: val.Float()}, true
+	default:
+		return number{}, false
+	}
+}
+
+// float returns the number as float64, which may round large integers.
+func (n number) float() float64 {
+	switch n.kind {
+	case intNumber:
+		return float64(n.i)
+	case uintNumber:
+		return float64(n.u)
+	default:
+		return n.f
+	}
+}
+
+// compare compares integers of the same signedness exactly, negative
+// integers are less than any unsigned one. Floats are used only if
+// one of the numbers is a float.
+func (n number) compare(other number) int {
+	switch {
+	case n.kind == floatNumber || other.kind == floatNumber:
+		return order(n.float() < other.float(), n.float() > other.float())
+	case n.kind == intNumber && other.kind == intNumber:
+		return order(n.i < other.i, n.i > other.i)
+	case n.kind == uintNumber && other.kind == uintNumber:
+		return order(n.u < other.u, n.u > other.u)
+	case n.kind == intNumber:
+		if n.i < 0 {
+			return -1
+		}
+		return order(uint64(n.i) < other.u, uint64(n.i) > other.u)
+	default:
+		return -other.compare(n)
+	}
+}
+
+// order returns the result of a comparison.
+func order(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// toFloat converts numbers of any type to float64.
+func toFloat(v any) (float64, bool) {
+	n, ok := toNumber(v)
+	return n.float(), ok
+}
+
+// Less is a comparison operator for values supported by Compare.
+func Less(a, b any) (BoolPredicate, error) {
+	return compareWith(a, b, func(c int) bool { return c < 0 })
+}
+
+// LessOrEqual is a comparison operator for values supported by Compare.
+func LessOrEqual(a, b any) (BoolPredicate, error) {
+	return compareWith(a, b, func(c int) bool { return c <= 0 })
+}
+
+// Greater is a comparison operator for values supported by Compare.
+func Greater(a, b any) (BoolPredicate, error) {
+	return compareWith(a, b, func(c int) bool { return c > 0 })
+}
+
+// GreaterOrEqual is a comparison operator for values supported by Compare.
+func GreaterOrEqual(a, b any) (BoolPredicate, error) {
+	return compareWith(a, b, func(c int) bool { return c >= 0 })
+}
+
+// EqualValues is an equality operator for values supported by Compare.
+// Unlike Equals, which compares strings and lists of strings, it compares
+// numbers of any type and Comparable values, so 1 and 1.0 or versions
+// 1.2.3 and v1.2.3 are equal, and it fails for values it can not compare.
+func EqualValues(a, b any) (BoolPredicate, error) {
+	return compareWith(a, b, func(c int) bool { return c == 0 })
+}
+
+// NotEqualValues is an inequality operator for values supported by Compare,
+// see EqualValues.
+func NotEqualValues(a, b any) (BoolPredicate, error) {
+	return compareWith(a, b, func(c int) bool { return c != 0 })
+}
+
+func compareWith(a, b any, fn func(int) bool) (BoolPredicate, error) {
+	c, err := Compare(a, b)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	result := fn(c)
+	return func() bool {
+		return result
+	}, nil
+}
+
+// FieldOptions controls optional traversal behavior of
+// GetFieldByTagWithOptions. The zero value only traverses struct fields,
+// which is the behavior of GetFieldByTag.
+type FieldOptions struct {
+	// Maps enables traversal into maps with string keys, the path segment
+	// is used as the map key, e.g. spec.labels.env.
+	Maps bool
+	// Slices enables traversal into slices and arrays, the path segment
+	// must be an index, e.g. spec.rules.0.name.
+	Slices bool
+	// Getters enables calling exported getter methods without arguments
+	// when no field matches, e.g. GetDisplayName() for display_name.
+	Getters bool
+	// Strict reports an error when more than one struct field matches
+	// a path segment instead of using the first match.
+	Strict bool
+}
+
+const (
+	// FieldNameTag is a pseudo tag name that matches struct fields by their
+	// Go name, e.g. DisplayName matches both DisplayName and display_name.
+	// Tag keys can not contain colons, so it never matches a real tag.
+	FieldNameTag = ":name"
+	// ProtobufTag is the tag set by the protobuf code generator, fields
+	// are matched by both name= and json= values of the tag, e.g.
+	// protobuf:"bytes,1,opt,name=display_name,json=displayName".
+	ProtobufTag = "protobuf"
+)
+
 // GetFieldByTag returns a field from the object based on the tag.
 func GetFieldByTag(ival any, tagName string, fieldNames []string) (any, error) {
-	i, err := getFieldByTag(reflect.ValueOf(ival), tagName, fieldNames)
+	return GetFieldByTagWithOptions(ival, tagName, fieldNames, FieldOptions{})
+}
+
+// GetFieldByTagWithOptions returns a field from the object based on the tag,
+// optionally traversing maps, slices and getter methods along the way.
+func GetFieldByTagWithOptions(ival any, tagName string, fieldNames []string, opts FieldOptions) (any, error) {
+	return GetFieldByTags(ival, []string{tagName}, fieldNames, opts)
+}
+
+// GetFieldByTags returns a field from the object trying the tags in order,
+// e.g. []string{"json", "yaml", ProtobufTag, FieldNameTag} resolves
+// protobuf-generated structs by their json, protobuf or Go field names.
+func GetFieldByTags(ival any, tags []string, fieldNames []string, opts FieldOptions) (any, error) {
+	if len(tags) == 0 {
+		return nil, trace.BadParameter("missing tag names")
+	}
+	w := fieldWalker{tags: tags, opts: opts}
+	i, err := w.walk(reflect.ValueOf(ival), fieldNames)
 	if err == nil {
 		return i, nil
 	}
 
 	// We use notFoundError instead of [trace.NotFoundError] within the
-	// recursive getFieldByTag function as an optimization since each call
+	// recursive walk function as an optimization since each call
 	// to [trace.NotFound] results in capturing the current stack trace.
 	// This is particularly important given how many incorrect branches we
 	// may have to take before we land on the correct path to the field.
@@ -161,49 +473,317 @@ func (n notFoundError) Error() string {
 	return fmt.Sprintf("field name %v is not found", strings.Join(n.fieldNames, "."))
 }
 
-func getFieldByTag(val reflect.Value, tagName string, fieldNames []string) (any, error) {
+// fieldWalker resolves field paths in values using reflection.
+type fieldWalker struct {
+	tags []string
+	opts FieldOptions
+}
+
+func (w *fieldWalker) walk(val reflect.Value, fieldNames []string) (any, error) {
 	if len(fieldNames) == 0 {
 		return nil, trace.BadParameter("missing field names")
 	}
+	for i := range fieldNames {
+		next, _, err := w.step(val, fieldNames[i:])
+		if err != nil {
+			return nil, err
+		}
+		val = next
+	}
+	if !val.IsValid() || !val.CanInterface() {
+		return nil, &notFoundError{fieldNames: fieldNames[len(fieldNames)-1:]}
+	}
+	return val.Interface(), nil
+}
+
+// step resolves the first of field names in the value, and returns the
+// value together with its canonical name: the Go name of struct fields
+// and getters, e.g. DisplayName for display_name, displayName and
+// GetDisplayName(), the map key, or the slice index.
+func (w *fieldWalker) step(val reflect.Value, fieldNames []string) (reflect.Value, string, error) {
+	next, name, getter, err := w.find(val, fieldNames)
+	if err != nil || !getter.IsValid() {
+		return next, name, err
+	}
+	next, err = callMethod("Get"+name, getter)
+	if err != nil {
+		return reflect.Value{}, "", trace.Wrap(err)
+	}
+	return next, name, nil
+}
 
+// find is step that returns getters instead of calling them, so canonical
+// names can be found without reading the fields they name.
+func (w *fieldWalker) find(val reflect.Value, fieldNames []string) (next reflect.Value, name string, getter reflect.Value, err error) {
+	// ptr keeps the last pointer seen, getters are often
+	// defined on pointer receivers.
+	var ptr reflect.Value
 	for val.Kind() == reflect.Interface || val.Kind() == reflect.Ptr {
 		if val.IsNil() {
-			return nil, &notFoundError{fieldNames: fieldNames}
+			return reflect.Value{}, "", reflect.Value{}, &notFoundError{fieldNames: fieldNames}
+		}
+		if val.Kind() == reflect.Ptr {
+			ptr = val
 		}
 		val = val.Elem()
 	}
 
-	if val.Kind() != reflect.Struct {
-		return nil, &notFoundError{fieldNames: fieldNames}
+	switch val.Kind() {
+	case reflect.Struct:
+		next, name, err := w.stepStruct(val, fieldNames)
+		if err == nil || !isNotFound(err) {
+			return next, name, reflect.Value{}, err
+		}
+	case reflect.Map:
+		if w.opts.Maps {
+			next, name, err := w.stepMap(val, fieldNames)
+			return next, name, reflect.Value{}, err
+		}
+	case reflect.Slice, reflect.Array:
+		if w.opts.Slices {
+			next, name, err := w.stepSlice(val, fieldNames)
+			return next, name, reflect.Value{}, err
+		}
 	}
-	fieldName := fieldNames[0]
-	rest := fieldNames[1:]
 
-	valType := val.Type()
-	for i := 0; i < valType.NumField(); i++ {
-		fieldType := valType.Field(i)
-		tagValue := fieldType.Tag.Get(tagName)
+	if w.opts.Getters {
+		if getter, ok := findGetter(fieldNames[0], ptr, val); ok {
+			return reflect.Value{}, exportedName(fieldNames[0]), getter, nil
+		}
+	}
+
+	return reflect.Value{}, "", reflect.Value{}, &notFoundError{fieldNames: fieldNames}
+}
 
-		// If it's an embedded field, traverse it.
-		if tagValue == "" && fieldType.Anonymous {
-			value := val.Field(i)
-			if valI, err := getFieldByTag(value, tagName, fieldNames); err == nil {
-				return valI, nil
+func (w *fieldWalker) stepStruct(val reflect.Value, fieldNames []string) (reflect.Value, string, error) {
+	// Tags are tried in order, fields of embedded structs without tags
+	// are matched in the same pass as the fields around them, so a field
+	// matched by an earlier tag wins no matter how deep it is. Among fields
+	// matched by the same tag, shallower fields shadow embedded ones, as
+	// they do in Go.
+	var matches []structField
+	for _, tag := range w.tags {
+		fields := shallowestFields(w.structFields(val, tag, fieldNames[0], "", 0))
+		if len(fields) != 0 && !w.opts.Strict {
+			return fields[0].value, fields[0].name, nil
+		}
+		for _, field := range fields {
+			if !containsField(matches, field.path) {
+				matches = append(matches, field)
 			}
 		}
+	}
 
-		if tagFieldName, _, _ := strings.Cut(tagValue, ","); tagFieldName == fieldName {
-			value := val.Field(i)
-			if len(rest) == 0 {
-				if value.CanInterface() {
-					return value.Interface(), nil
+	switch len(matches) {
+	case 0:
+		return reflect.Value{}, "", &notFoundError{fieldNames: fieldNames}
+	case 1:
+		return matches[0].value, matches[0].name, nil
+	default:
+		paths := make([]string, 0, len(matches))
+		for _, m := range matches {
+			paths = append(paths, m.path)
+		}
+		return reflect.Value{}, "", trace.BadParameter("field name %v is ambiguous, it matches fields %v",
+			strings.Join(fieldNames, "."), strings.Join(paths, ", "))
+	}
+}
+
+// structField is a struct field matched by a name.
+type structField struct {
+	// path is the Go path of the field, e.g. Metadata.Name
+	// for fields of embedded structs.
+	path string
+	// name is the Go name of the field, e.g. Name.
+	name  string
+	value reflect.Value
+	// depth is the number of embedded structs the field is in.
+	depth int
+}
+
+// shallowestFields returns the fields with the lowest depth, in order.
+func shallowestFields(fields []structField) []structField {
+	var out []structField
+	for _, f := range fields {
+		switch {
+		case len(out) == 0 || f.depth == out[0].depth:
+			out = append(out, f)
+		case f.depth < out[0].depth:
+			out = append(out[:0], f)
+		}
+	}
+	return out
+}
+
+// structFields returns fields named fieldName by the tag, in field order,
+// including fields of embedded structs without tags.
+func (w *fieldWalker) structFields(val reflect.Value, tag, fieldName, prefix string, depth int) []structField {
+	var out []structField
+	valType := val.Type()
+	for i := 0; i < valType.NumField(); i++ {
+		fieldType := valType.Field(i)
+		if fieldType.Anonymous && !w.hasTag(fieldType) {
+			embedded := val.Field(i)
+			for embedded.Kind() == reflect.Interface || embedded.Kind() == reflect.Ptr {
+				if embedded.IsNil() {
+					break
 				}
-				return nil, &notFoundError{fieldNames: fieldNames}
+				embedded = embedded.Elem()
 			}
+			if embedded.Kind() == reflect.Struct {
+				out = append(out, w.structFields(embedded, tag, fieldName, prefix+fieldType.Name+".", depth+1)...)
+			}
+			continue
+		}
+		if fieldMatches(fieldType, tag, fieldName) {
+			out = append(out, structField{path: prefix + fieldType.Name, name: fieldType.Name, value: val.Field(i), depth: depth})
+		}
+	}
+	return out
+}
 
-			return getFieldByTag(value, tagName, rest)
+func containsField(fields []structField, path string) bool {
+	for _, f := range fields {
+		if f.path == path {
+			return true
 		}
 	}
+	return false
+}
 
-	return nil, &notFoundError{fieldNames: fieldNames}
+// hasTag returns true if the field has a name in any of the walker tags.
+func (w *fieldWalker) hasTag(field reflect.StructField) bool {
+	for _, tag := range w.tags {
+		if tag != FieldNameTag && len(tagNames(field, tag)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldMatches returns true if the field is named fieldName by the tag.
+func fieldMatches(field reflect.StructField, tag, fieldName string) bool {
+	if tag == FieldNameTag {
+		return !field.Anonymous && field.IsExported() &&
+			(field.Name == fieldName || field.Name == exportedName(fieldName))
+	}
+	return containsString(tagNames(field, tag), fieldName)
+}
+
+// tagNames returns names given to the field by the tag.
+func tagNames(field reflect.StructField, tag string) []string {
+	tagValue := field.Tag.Get(tag)
+	if tagValue == "" {
+		return nil
+	}
+	if tag != ProtobufTag {
+		name, _, _ := strings.Cut(tagValue, ",")
+		if name == "" {
+			return nil
+		}
+		return []string{name}
+	}
+	var names []string
+	for _, part := range strings.Split(tagValue, ",") {
+		key, value, ok := strings.Cut(part, "=")
+		if ok && (key == "name" || key == "json") && value != "" {
+			names = append(names, value)
+		}
+	}
+	return names
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *fieldWalker) stepMap(val reflect.Value, fieldNames []string) (reflect.Value, string, error) {
+	keyType := val.Type().Key()
+	if keyType.Kind() != reflect.String {
+		return reflect.Value{}, "", &notFoundError{fieldNames: fieldNames}
+	}
+	value := val.MapIndex(reflect.ValueOf(fieldNames[0]).Convert(keyType))
+	if !value.IsValid() {
+		return reflect.Value{}, "", &notFoundError{fieldNames: fieldNames}
+	}
+	return value, fieldNames[0], nil
+}
+
+func (w *fieldWalker) stepSlice(val reflect.Value, fieldNames []string) (reflect.Value, string, error) {
+	index, err := strconv.Atoi(fieldNames[0])
+	if err != nil || index < 0 || index >= val.Len() {
+		return reflect.Value{}, "", &notFoundError{fieldNames: fieldNames}
+	}
+	return val.Index(index), strconv.Itoa(index), nil
+}
+
+// findGetter returns the getter method for the field name, e.g. GetName
+// for name, of the first of values that has it. Only exported methods
+// without arguments returning a value, optionally followed by an error,
+// are used.
+func findGetter(fieldName string, values ...reflect.Value) (reflect.Value, bool) {
+	name := "Get" + exportedName(fieldName)
+	for _, v := range values {
+		if !v.IsValid() || !v.CanInterface() {
+			continue
+		}
+		method := v.MethodByName(name)
+		if !method.IsValid() {
+			continue
+		}
+		methodType := method.Type()
+		if methodType.NumIn() != 0 || methodType.NumOut() == 0 || methodType.NumOut() > 2 {
+			continue
+		}
+		if methodType.NumOut() == 2 && methodType.Out(1) != errorType {
+			continue
+		}
+		return method, true
+	}
+	return reflect.Value{}, false
+}
+
+// callMethod calls the getter method, converting panics to errors.
+func callMethod(name string, method reflect.Value) (out reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = trace.BadParameter("%s() failed: %v", name, r)
+		}
+	}()
+	ret := method.Call(nil)
+	if len(ret) == 2 && !ret[1].IsNil() {
+		return reflect.Value{}, ret[1].Interface().(error)
+	}
+	return ret[0], nil
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// exportedName converts field names like display_name
+// or displayName into exported Go names like DisplayName.
+func exportedName(fieldName string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range fieldName {
+		if r == '_' || r == '-' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func isNotFound(err error) bool {
+	var nfe *notFoundError
+	return errors.As(err, &nfe)
 }
@@ -0,0 +1,47 @@
+package predicate
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		a, b   any
+		expect int
+	}{
+		{a: 1, b: 2, expect: -1},
+		{a: 2.5, b: 2, expect: 1},
+		{a: int64(3), b: uint8(3), expect: 0},
+		{a: int64(9007199254740993), b: int64(9007199254740992), expect: 1},
+		{a: uint64(math.MaxUint64), b: uint64(math.MaxUint64 - 1), expect: 1},
+		{a: int64(-1), b: uint64(math.MaxUint64), expect: -1},
+		{a: uint64(math.MaxUint64), b: int64(math.MaxInt64), expect: 1},
+		{a: json.Number("9007199254740993"), b: int64(9007199254740992), expect: 1},
+		{a: json.Number("18446744073709551615"), b: uint64(math.MaxUint64 - 1), expect: 1},
+		{a: 1.5, b: uint64(1), expect: 1},
+		{a: "b", b: "a", expect: 1},
+		{a: "1.2.3", b: SemVer{Major: 1, Minor: 10}, expect: -1},
+	} {
+		c, err := Compare(tc.a, tc.b)
+		require.NoError(t, err)
+		require.Equal(t, tc.expect, c, "Compare(%v, %v)", tc.a, tc.b)
+	}
+
+	for _, tc := range []struct {
+		a, b any
+	}{
+		{a: "1", b: 1},
+		{a: []string{"a"}, b: []string{"a"}},
+		{a: SemVer{}, b: 1},
+	} {
+		_, err := Compare(tc.a, tc.b)
+		require.True(t, trace.IsBadParameter(err), "Compare(%v, %v): unexpected error %v", tc.a, tc.b, err)
+	}
+}
@@ -0,0 +1,345 @@
+package lint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+
+	"github.com/vulcand/predicate"
+	"github.com/vulcand/predicate/internal/astutil"
+)
+
+// DefaultChecks returns all checks of this package.
+func DefaultChecks() []Check {
+	return []Check{
+		Tautology(),
+		Contradiction(),
+		DuplicateClause(),
+		LiteralComparison(),
+		UnknownFunction(),
+		Deprecated(),
+		ListStringEquality(),
+		EqualsTypeMismatch(),
+	}
+}
+
+// Tautology reports conditions that are always true, e.g. x == x or a || !a.
+func Tautology() Check {
+	return Check{
+		Name:     "tautology",
+		Severity: Warning,
+		Run: func(pass *Pass, expr ast.Expr) {
+			if bin, ok := expr.(*ast.BinaryExpr); ok {
+				switch bin.Op {
+				case token.EQL, token.LEQ, token.GEQ:
+					if sameOperands(bin) {
+						pass.Report(bin, "%v is always true", astutil.ExprString(bin))
+					}
+				case token.LOR:
+					reportNegatedOperands(pass, bin, "is always true")
+				}
+			}
+		},
+	}
+}
+
+// Contradiction reports conditions that are always false, e.g. x != x or a && !a.
+func Contradiction() Check {
+	return Check{
+		Name:     "contradiction",
+		Severity: Error,
+		Run: func(pass *Pass, expr ast.Expr) {
+			if bin, ok := expr.(*ast.BinaryExpr); ok {
+				switch bin.Op {
+				case token.NEQ, token.LSS, token.GTR:
+					if sameOperands(bin) {
+						pass.Report(bin, "%v is always false", astutil.ExprString(bin))
+					}
+				case token.LAND:
+					reportNegatedOperands(pass, bin, "is always false")
+				}
+			}
+		},
+	}
+}
+
+// DuplicateClause reports operands repeated in chains of && or ||, e.g. a && b && a.
+func DuplicateClause() Check {
+	return Check{
+		Name:     "duplicate-clause",
+		Severity: Warning,
+		Run: func(pass *Pass, expr ast.Expr) {
+			bin, ok := chainTop(pass, expr)
+			if !ok {
+				return
+			}
+			seen := make(map[string]struct{})
+			for _, operand := range astutil.LogicalOperands(bin.Op, bin) {
+				key := astutil.ExprString(operand)
+				if _, ok := seen[key]; ok {
+					pass.Report(operand, "duplicate clause %v", key)
+					continue
+				}
+				seen[key] = struct{}{}
+			}
+		},
+	}
+}
+
+// LiteralComparison reports comparisons of two literals, e.g. "a" == "a",
+// their result does not depend on the input.
+func LiteralComparison() Check {
+	return Check{
+		Name:     "literal-comparison",
+		Severity: Warning,
+		Run: func(pass *Pass, expr ast.Expr) {
+			bin, ok := expr.(*ast.BinaryExpr)
+			if !ok || !isComparison(bin.Op) {
+				return
+			}
+			_, xok := astutil.Unparen(bin.X).(*ast.BasicLit)
+			_, yok := astutil.Unparen(bin.Y).(*ast.BasicLit)
+			if xok && yok {
+				pass.Report(bin, "comparison of literals %v is constant", astutil.ExprString(bin))
+			}
+		},
+	}
+}
+
+// UnknownFunction reports calls of functions and methods missing from Def.
+func UnknownFunction() Check {
+	return Check{
+		Name:     "unknown-function",
+		Severity: Error,
+		Run: func(pass *Pass, expr ast.Expr) {
+			call, ok := expr.(*ast.CallExpr)
+			if !ok {
+				return
+			}
+			if _, _, ok := pass.Function(call); !ok {
+				pass.Report(call.Fun, "unknown function %v", astutil.ExprString(call.Fun))
+			}
+		},
+	}
+}
+
+// Deprecated reports calls of functions marked deprecated in Def.FunctionInfo.
+func Deprecated() Check {
+	return Check{
+		Name:     "deprecated",
+		Severity: Warning,
+		Run: func(pass *Pass, expr ast.Expr) {
+			call, ok := expr.(*ast.CallExpr)
+			if !ok {
+				return
+			}
+			name, _, ok := pass.Function(call)
+			if !ok {
+				return
+			}
+			if msg := pass.Def.FunctionInfo[name].Deprecated; msg != "" {
+				pass.Report(call.Fun, "%v is deprecated: %v", name, msg)
+			}
+		},
+	}
+}
+
+// ListStringEquality reports == and != between a list and a string,
+// which is likely meant to be a membership test with contains.
+func ListStringEquality() Check {
+	return Check{
+		Name:     "list-string-equality",
+		Severity: Warning,
+		Run: func(pass *Pass, expr ast.Expr) {
+			bin, ok := expr.(*ast.BinaryExpr)
+			if !ok || (bin.Op != token.EQL && bin.Op != token.NEQ) {
+				return
+			}
+			xt, xok := pass.TypeOf(bin.X)
+			yt, yok := pass.TypeOf(bin.Y)
+			if xok && yok && isListStringPair(xt, yt) {
+				pass.Report(bin, "%v compares a list with a string, use contains to test membership", astutil.ExprString(bin))
+			}
+		},
+	}
+}
+
+// EqualsTypeMismatch reports uses of predicate.Equals, as a function or as
+// the == operator, with operands it can never find equal: Equals compares
+// strings and lists of strings, values of other types are never equal.
+func EqualsTypeMismatch() Check {
+	return Check{
+		Name:     "equals-type-mismatch",
+		Severity: Error,
+		Run: func(pass *Pass, expr ast.Expr) {
+			var a, b ast.Expr
+			switch n := expr.(type) {
+			case *ast.BinaryExpr:
+				if n.Op != token.EQL || !isEquals(pass.Def.Operators.EQ) {
+					return
+				}
+				a, b = n.X, n.Y
+			case *ast.CallExpr:
+				_, fn, ok := pass.Function(n)
+				if !ok || !isEquals(fn) || len(n.Args) != 2 {
+					return
+				}
+				a, b = n.Args[0], n.Args[1]
+			default:
+				return
+			}
+			at, aok := pass.TypeOf(a)
+			bt, bok := pass.TypeOf(b)
+			for _, t := range []struct {
+				typ reflect.Type
+				ok  bool
+			}{{at, aok}, {bt, bok}} {
+				if t.ok && t.typ != stringType && t.typ != stringsType {
+					pass.Report(expr, "%v is always false, equals does not compare %v values", astutil.ExprString(expr), t.typ)
+					return
+				}
+			}
+			// Lists compared with strings are reported by ListStringEquality.
+			if aok && bok && at != bt && !isListStringPair(at, bt) {
+				pass.Report(expr, "%v is always false, %v is never equal to %v", astutil.ExprString(expr), at, bt)
+			}
+		},
+	}
+}
+
+var (
+	stringType  = reflect.TypeOf("")
+	stringsType = reflect.TypeOf([]string{})
+)
+
+func isListStringPair(a, b reflect.Type) bool {
+	isList := func(t reflect.Type) bool {
+		return t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+	}
+	isString := func(t reflect.Type) bool {
+		return t.Kind() == reflect.String
+	}
+	return (isList(a) && isString(b)) || (isString(a) && isList(b))
+}
+
+// isEquals returns true if fn is predicate.Equals.
+func isEquals(fn any) bool {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return false
+	}
+	return v.Pointer() == reflect.ValueOf(predicate.Equals).Pointer()
+}
+
+func isComparison(op token.Token) bool {
+	switch op {
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		return true
+	default:
+		return false
+	}
+}
+
+// sameOperands returns true if both operands are the same expression
+// other than a literal, literals are reported by LiteralComparison.
+func sameOperands(bin *ast.BinaryExpr) bool {
+	if _, ok := astutil.Unparen(bin.X).(*ast.BasicLit); ok {
+		return false
+	}
+	return astutil.ExprString(bin.X) == astutil.ExprString(bin.Y)
+}
+
+// reportNegatedOperands reports operands of the chain that are
+// negations of other operands.
+func reportNegatedOperands(pass *Pass, bin *ast.BinaryExpr, msg string) {
+	top, ok := chainTop(pass, bin)
+	if !ok {
+		return
+	}
+	operands := astutil.LogicalOperands(top.Op, top)
+	seen := make(map[string]struct{}, len(operands))
+	for _, operand := range operands {
+		seen[astutil.ExprString(operand)] = struct{}{}
+	}
+	for _, operand := range operands {
+		not, ok := astutil.Unparen(operand).(*ast.UnaryExpr)
+		if !ok || not.Op != token.NOT {
+			continue
+		}
+		if _, ok := seen[astutil.ExprString(not.X)]; ok {
+			pass.Report(top, "%v %v, %v is negated in the same clause", astutil.ExprString(top), msg, astutil.ExprString(not.X))
+			return
+		}
+	}
+}
+
+// chainTop returns the expression if it is the outermost operator
+// of a chain of && or ||.
+func chainTop(pass *Pass, expr ast.Expr) (*ast.BinaryExpr, bool) {
+	bin, ok := expr.(*ast.BinaryExpr)
+	if !ok || (bin.Op != token.LAND && bin.Op != token.LOR) {
+		return nil, false
+	}
+	if parent, ok := pass.Parent(bin).(*ast.BinaryExpr); ok && parent.Op == bin.Op {
+		return nil, false
+	}
+	return bin, true
+}
+
+// Function returns the name and the implementation of the called function
+// or method, resolved the same way the parser does, and false if it is not
+// defined.
+func (p *Pass) Function(call *ast.CallExpr) (string, any, bool) {
+	switch f := call.Fun.(type) {
+	case *ast.Ident:
+		fn, ok := p.Def.Functions[f.Name]
+		return f.Name, fn, ok
+	case *ast.SelectorExpr:
+		if fn, ok := p.Def.Methods[f.Sel.Name]; ok {
+			return f.Sel.Name, fn, true
+		}
+		id, ok := f.X.(*ast.Ident)
+		if !ok {
+			return "", nil, false
+		}
+		name := fmt.Sprintf("%s.%s", id.Name, f.Sel.Name)
+		fn, ok := p.Def.Functions[name]
+		return name, fn, ok
+	default:
+		return "", nil, false
+	}
+}
+
+// TypeOf returns the static type of the expression if it is known:
+// the type of literals, the result type of functions, and the type
+// of identifiers returned by Config.IdentifierType.
+func (p *Pass) TypeOf(expr ast.Expr) (reflect.Type, bool) {
+	switch n := astutil.Unparen(expr).(type) {
+	case *ast.BasicLit:
+		switch n.Kind {
+		case token.STRING:
+			return stringType, true
+		case token.INT:
+			return reflect.TypeOf(0), true
+		case token.FLOAT:
+			return reflect.TypeOf(0.0), true
+		}
+	case *ast.Ident, *ast.SelectorExpr:
+		selector, ok := astutil.SelectorOf(n)
+		if ok && p.cfg.IdentifierType != nil {
+			return p.cfg.IdentifierType(selector)
+		}
+	case *ast.CallExpr:
+		_, fn, ok := p.Function(n)
+		if !ok {
+			return nil, false
+		}
+		t := reflect.TypeOf(fn)
+		if t == nil || t.Kind() != reflect.Func || t.NumOut() == 0 || t.Out(0).Kind() == reflect.Interface {
+			return nil, false
+		}
+		return t.Out(0), true
+	}
+	return nil, false
+}
@@ -0,0 +1,213 @@
+// Package lint runs diagnostics over predicate expressions, e.g. to warn
+// rule authors about contradictions or calls to unknown functions before
+// the rules are saved.
+package lint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"reflect"
+	"sort"
+
+	"github.com/gravitational/trace"
+	"github.com/vulcand/predicate"
+	"github.com/vulcand/predicate/internal/astutil"
+)
+
+// Severity is the severity of a diagnostic.
+type Severity int
+
+const (
+	// Info is a suggestion.
+	Info Severity = iota
+	// Warning is likely a mistake.
+	Warning
+	// Error is a rule that does not work as intended.
+	Error
+)
+
+// String returns info, warning or error.
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// Diagnostic is a problem found by a check.
+type Diagnostic struct {
+	// Check is the name of the check that reported the problem.
+	Check string
+	// Severity is the severity of the check.
+	Severity Severity
+	// Message describes the problem.
+	Message string
+	// Span is the location of the problem.
+	Span predicate.Span
+}
+
+// String returns the diagnostic in line:column: severity: message (check) format.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%v: %v: %v (%v)", d.Span.Start, d.Severity, d.Message, d.Check)
+}
+
+// Check is a diagnostic run over expressions.
+type Check struct {
+	// Name identifies the check, e.g. duplicate-clause.
+	Name string
+	// Severity is the severity of reported diagnostics.
+	Severity Severity
+	// Run is called for every subexpression of the expression,
+	// except parentheses, and reports problems with Pass.Report.
+	Run func(pass *Pass, expr ast.Expr)
+}
+
+// Config configures the linter.
+type Config struct {
+	// Def is the definition rules are parsed with, it is used to check
+	// function names and types.
+	Def predicate.Def
+	// Checks are the checks to run, DefaultChecks if nil.
+	Checks []Check
+	// IdentifierType optionally returns the type of identifier values,
+	// e.g. []string for user.traits.logins, to check operand types.
+	IdentifierType func(selector []string) (reflect.Type, bool)
+}
+
+// CheckAndSetDefaults checks and sets default values.
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Checks == nil {
+		c.Checks = DefaultChecks()
+	}
+	return nil
+}
+
+// Linter runs checks over expressions.
+type Linter struct {
+	cfg    Config
+	checks []Check
+}
+
+// NewLinter returns a linter running the configured checks.
+func NewLinter(cfg Config) (*Linter, error) {
+	if err := cfg.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	l := &Linter{cfg: cfg}
+	if err := l.Register(cfg.Checks...); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return l, nil
+}
+
+// Register adds checks to the linter, e.g. host specific ones.
+// Names of checks must be unique.
+func (l *Linter) Register(checks ...Check) error {
+	for _, c := range checks {
+		if c.Name == "" || c.Run == nil {
+			return trace.BadParameter("check needs a name and a run function")
+		}
+		for _, existing := range l.checks {
+			if existing.Name == c.Name {
+				return trace.AlreadyExists("check %q is already registered", c.Name)
+			}
+		}
+		l.checks = append(l.checks, c)
+	}
+	return nil
+}
+
+// Lint parses the expression and returns diagnostics of all checks
+// ordered by position.
+func (l *Linter) Lint(in string) ([]Diagnostic, error) {
+	expr, err := parser.ParseExpr(in)
+	if err != nil {
+		return nil, err
+	}
+	parents := make(map[ast.Expr]ast.Expr)
+	var nodes []ast.Expr
+	var stack []ast.Expr
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return false
+		}
+		e, ok := n.(ast.Expr)
+		if !ok {
+			stack = append(stack, nil)
+			return true
+		}
+		if _, paren := e.(*ast.ParenExpr); !paren {
+			nodes = append(nodes, e)
+			for i := len(stack) - 1; i >= 0; i-- {
+				if _, paren := stack[i].(*ast.ParenExpr); stack[i] != nil && !paren {
+					parents[e] = stack[i]
+					break
+				}
+			}
+		}
+		stack = append(stack, e)
+		return true
+	})
+
+	var diags []Diagnostic
+	for _, c := range l.checks {
+		pass := &Pass{
+			Def:     l.cfg.Def,
+			In:      in,
+			check:   c,
+			cfg:     l.cfg,
+			parents: parents,
+			diags:   &diags,
+		}
+		for _, node := range nodes {
+			c.Run(pass, node)
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].Span.Start.Offset < diags[j].Span.Start.Offset
+	})
+	return diags, nil
+}
+
+// Pass is the state of a check run over an expression.
+type Pass struct {
+	// Def is the definition from the linter config.
+	Def predicate.Def
+	// In is the expression text.
+	In string
+
+	check   Check
+	cfg     Config
+	parents map[ast.Expr]ast.Expr
+	diags   *[]Diagnostic
+}
+
+// Report reports a problem with the node.
+func (p *Pass) Report(node ast.Node, format string, args ...any) {
+	*p.diags = append(*p.diags, Diagnostic{
+		Check:    p.check.Name,
+		Severity: p.check.Severity,
+		Message:  fmt.Sprintf(format, args...),
+		Span:     spanOf(p.In, node),
+	})
+}
+
+// Parent returns the closest enclosing expression other than parentheses,
+// nil for the whole expression.
+func (p *Pass) Parent(expr ast.Expr) ast.Expr {
+	return p.parents[expr]
+}
+
+// spanOf returns the span of the node of the expression parsed from in.
+func spanOf(in string, node ast.Node) predicate.Span {
+	return predicate.Span{
+		Start: predicate.Position(astutil.PositionOf(in, node.Pos())),
+		End:   predicate.Position(astutil.PositionOf(in, node.End())),
+	}
+}
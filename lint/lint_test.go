@@ -0,0 +1,172 @@
+package lint
+
+import (
+	"go/ast"
+	"go/scanner"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+	"github.com/vulcand/predicate"
+	"github.com/vulcand/predicate/internal/astutil"
+)
+
+func testConfig() Config {
+	return Config{
+		Def: predicate.Def{
+			Operators: predicate.Operators{
+				AND: predicate.And,
+				OR:  predicate.Or,
+				NOT: predicate.Not,
+				EQ:  predicate.Equals,
+			},
+			Functions: map[string]any{
+				"equals":   predicate.Equals,
+				"contains": predicate.Contains,
+				"prefix": func(a, b string) predicate.BoolPredicate {
+					return func() bool { return strings.HasPrefix(a, b) }
+				},
+				"strings.hasPrefix": func(a, b string) predicate.BoolPredicate {
+					return func() bool { return strings.HasPrefix(a, b) }
+				},
+			},
+			Methods: map[string]any{
+				"lower": strings.ToLower,
+			},
+			FunctionInfo: map[string]predicate.FunctionInfo{
+				"prefix": {Deprecated: "use strings.hasPrefix instead"},
+			},
+		},
+		IdentifierType: func(selector []string) (reflect.Type, bool) {
+			switch strings.Join(selector, ".") {
+			case "user.name":
+				return reflect.TypeOf(""), true
+			case "user.logins":
+				return reflect.TypeOf([]string{}), true
+			case "user.age":
+				return reflect.TypeOf(0), true
+			}
+			return nil, false
+		},
+	}
+}
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{in: `user.name == "alice" && contains(user.logins, "root")`},
+		{in: `user.name == user.name`, want: []string{
+			"1:1: warning: user.name == user.name is always true (tautology)",
+		}},
+		{in: `user.name != (user.name)`, want: []string{
+			"1:1: error: user.name != user.name is always false (contradiction)",
+		}},
+		{in: `a || b || !a`, want: []string{
+			"1:1: warning: a || b || !a is always true, a is negated in the same clause (tautology)",
+		}},
+		{in: `x && (y && !(x))`, want: []string{
+			"1:1: error: x && (y && !x) is always false, x is negated in the same clause (contradiction)",
+		}},
+		{in: `a && b || a && b`, want: []string{
+			"1:11: warning: duplicate clause a && b (duplicate-clause)",
+		}},
+		{in: `a && b && c && b`, want: []string{
+			"1:16: warning: duplicate clause b (duplicate-clause)",
+		}},
+		{in: `"a" == "a"`, want: []string{
+			`1:1: warning: comparison of literals "a" == "a" is constant (literal-comparison)`,
+		}},
+		{in: `missing(a) || user.missing(b) || user.name.lower() == "x"`, want: []string{
+			"1:1: error: unknown function missing (unknown-function)",
+			"1:15: error: unknown function user.missing (unknown-function)",
+		}},
+		{in: `prefix(user.name, "a")`, want: []string{
+			"1:1: warning: prefix is deprecated: use strings.hasPrefix instead (deprecated)",
+		}},
+		{in: `user.logins == "root"`, want: []string{
+			`1:1: warning: user.logins == "root" compares a list with a string, use contains to test membership (list-string-equality)`,
+		}},
+		{in: `user.age == 10`, want: []string{
+			"1:1: error: user.age == 10 is always false, equals does not compare int values (equals-type-mismatch)",
+		}},
+		{in: `equals(user.name, 1.5)`, want: []string{
+			"1:1: error: equals(user.name, 1.5) is always false, equals does not compare float64 values (equals-type-mismatch)",
+		}},
+		{in: `equals(user.name.lower(), "a") && equals(unknown, 1)`, want: []string{
+			"1:35: error: equals(unknown, 1) is always false, equals does not compare int values (equals-type-mismatch)",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			l, err := NewLinter(testConfig())
+			require.NoError(t, err)
+			diags, err := l.Lint(tt.in)
+			require.NoError(t, err)
+			var out []string
+			for _, d := range diags {
+				out = append(out, d.String())
+			}
+			require.Equal(t, tt.want, out)
+		})
+	}
+}
+
+func TestLintSpan(t *testing.T) {
+	t.Parallel()
+
+	l, err := NewLinter(testConfig())
+	require.NoError(t, err)
+	diags, err := l.Lint("a &&\n\tuser.name != user.name")
+	require.NoError(t, err)
+	require.Len(t, diags, 1)
+	require.Equal(t, "contradiction", diags[0].Check)
+	require.Equal(t, Error, diags[0].Severity)
+	require.Equal(t, predicate.Position{Offset: 6, Line: 2, Column: 2}, diags[0].Span.Start)
+	require.Equal(t, predicate.Position{Offset: 28, Line: 2, Column: 24}, diags[0].Span.End)
+
+	// Syntax errors are returned as go/parser reports them.
+	_, err = l.Lint(`a &&`)
+	require.IsType(t, scanner.ErrorList{}, err)
+}
+
+func TestLintRegister(t *testing.T) {
+	t.Parallel()
+
+	noRoot := Check{
+		Name:     "no-root",
+		Severity: Info,
+		Run: func(pass *Pass, expr ast.Expr) {
+			if lit, ok := expr.(*ast.BasicLit); ok && lit.Value == `"root"` {
+				if call, ok := pass.Parent(lit).(*ast.CallExpr); ok {
+					pass.Report(lit, "%v matches root", astutil.ExprString(call))
+				}
+			}
+		},
+	}
+	l, err := NewLinter(Config{Def: testConfig().Def, Checks: []Check{UnknownFunction()}})
+	require.NoError(t, err)
+	require.NoError(t, l.Register(noRoot))
+
+	diags, err := l.Lint(`(contains(user.logins, ("root"))) || user.name == "root"`)
+	require.NoError(t, err)
+	require.Equal(t, []Diagnostic{{
+		Check:    "no-root",
+		Severity: Info,
+		Message:  `contains(user.logins, "root") matches root`,
+		Span: predicate.Span{
+			Start: predicate.Position{Offset: 24, Line: 1, Column: 25},
+			End:   predicate.Position{Offset: 30, Line: 1, Column: 31},
+		},
+	}}, diags)
+
+	err = l.Register(noRoot)
+	require.True(t, trace.IsAlreadyExists(err))
+	err = l.Register(Check{Name: "empty"})
+	require.True(t, trace.IsBadParameter(err))
+}
@@ -0,0 +1,164 @@
+package predicate
+
+import (
+	"net/netip"
+
+	"github.com/gravitational/trace"
+)
+
+// NetworkModule returns IP address and CIDR functions:
+//
+//	cidrContains(cidr, ip)       - returns BoolPredicate,
+//	                               e.g. cidrContains("10.0.0.0/8", request.ip)
+//	inAnyCIDR(ip, cidr, ...)     - returns BoolPredicate, CIDRs are listed as
+//	                               arguments, e.g. inAnyCIDR(request.ip,
+//	                               "10.0.0.0/8", "192.168.0.0/16"), or passed
+//	                               as a single []string identifier
+//	isPrivate(ip)                - returns BoolPredicate, true for RFC 1918
+//	                               and RFC 4193 addresses
+//	ipVersion(ip)                - returns 4 or 6
+//
+// IPv4-mapped IPv6 addresses like ::ffff:10.0.0.1 are treated as the IPv4
+// addresses they map, both in addresses and in CIDRs. The language has no
+// list literals, so lists of CIDRs written in rules are passed as separate
+// arguments.
+func NetworkModule() Module {
+	return Module{
+		Functions: map[string]any{
+			"cidrContains": cidrContains,
+			"inAnyCIDR":    inAnyCIDR,
+			"isPrivate":    isPrivate,
+			"ipVersion":    ipVersion,
+		},
+		FunctionInfo: map[string]FunctionInfo{
+			"cidrContains": {Literals: []LiteralFunc{parsePrefixLiteral, parseAddrLiteral}, Pure: true},
+			"inAnyCIDR":    {Literals: []LiteralFunc{parseAddrLiteral, parsePrefixLiteral}, Pure: true, Cost: inAnyCIDRCost},
+			"isPrivate":    {Literals: []LiteralFunc{parseAddrLiteral}, Pure: true},
+			"ipVersion":    {Literals: []LiteralFunc{parseAddrLiteral}, Pure: true},
+		},
+	}
+}
+
+// inAnyCIDRCost is linear in the number of CIDRs, passed as separate
+// arguments, or as a single list argument of the given length.
+var inAnyCIDRCost = &Cost{Estimate: func(lengths []int) float64 {
+	if len(lengths) == 2 {
+		return 1 + float64(lengths[1])
+	}
+	return float64(len(lengths))
+}}
+
+func parsePrefixLiteral(v any) (any, error) {
+	return toPrefix(v)
+}
+
+func parseAddrLiteral(v any) (any, error) {
+	return toAddr(v)
+}
+
+// toAddr converts a string or netip.Addr to an address,
+// IPv4-mapped IPv6 addresses are converted to IPv4.
+func toAddr(v any) (netip.Addr, error) {
+	switch a := v.(type) {
+	case netip.Addr:
+		return a.Unmap(), nil
+	case string:
+		addr, err := netip.ParseAddr(a)
+		if err != nil {
+			return netip.Addr{}, trace.BadParameter("invalid IP address %q", a)
+		}
+		return addr.Unmap(), nil
+	default:
+		return netip.Addr{}, trace.BadParameter("expected IP address to be a string, got %T", v)
+	}
+}
+
+// toPrefix converts a string or netip.Prefix to a masked prefix,
+// IPv4-mapped IPv6 prefixes are converted to IPv4.
+func toPrefix(v any) (netip.Prefix, error) {
+	var prefix netip.Prefix
+	switch p := v.(type) {
+	case netip.Prefix:
+		prefix = p
+	case string:
+		var err error
+		prefix, err = netip.ParsePrefix(p)
+		if err != nil {
+			return netip.Prefix{}, trace.BadParameter("invalid CIDR %q", p)
+		}
+	default:
+		return netip.Prefix{}, trace.BadParameter("expected CIDR to be a string, got %T", v)
+	}
+	if addr := prefix.Addr(); addr.Is4In6() {
+		if prefix.Bits() < 96 {
+			return netip.Prefix{}, trace.BadParameter("CIDR %v is wider than IPv4-mapped address range", prefix)
+		}
+		prefix = netip.PrefixFrom(addr.Unmap(), prefix.Bits()-96)
+	}
+	return prefix.Masked(), nil
+}
+
+func cidrContains(cidr, ip any) (BoolPredicate, error) {
+	prefix, err := toPrefix(cidr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	addr, err := toAddr(ip)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return func() bool {
+		return prefix.Contains(addr)
+	}, nil
+}
+
+func inAnyCIDR(ip any, cidrs ...any) (BoolPredicate, error) {
+	addr, err := toAddr(ip)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(cidrs) == 1 {
+		if list, ok := cidrs[0].([]string); ok {
+			cidrs = make([]any, len(list))
+			for i := range list {
+				cidrs[i] = list[i]
+			}
+		}
+	}
+	prefixes := make([]netip.Prefix, len(cidrs))
+	for i := range cidrs {
+		prefixes[i], err = toPrefix(cidrs[i])
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return func() bool {
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func isPrivate(ip any) (BoolPredicate, error) {
+	addr, err := toAddr(ip)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return func() bool {
+		return addr.IsPrivate()
+	}, nil
+}
+
+func ipVersion(ip any) (int, error) {
+	addr, err := toAddr(ip)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	if addr.Is4() {
+		return 4, nil
+	}
+	return 6, nil
+}
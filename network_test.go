@@ -0,0 +1,64 @@
+package predicate
+
+import (
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkModule(t *testing.T) {
+	t.Parallel()
+
+	p := newTestParser(t, Def{}, map[string]any{
+		"v4":       "10.1.2.3",
+		"mapped":   "::ffff:10.1.2.3",
+		"public":   "8.8.8.8",
+		"v6":       "fd00::1",
+		"invalid":  "10.1.2",
+		"networks": []string{"192.168.0.0/16", "10.0.0.0/8"},
+	}, NetworkModule())
+
+	for _, tc := range []struct {
+		desc   string
+		input  string
+		expect any
+	}{
+		{desc: "contains", input: `cidrContains("10.0.0.0/8", request.v4)`, expect: true},
+		{desc: "does not contain", input: `cidrContains("192.168.0.0/16", request.v4)`, expect: false},
+		{desc: "mapped address in IPv4 CIDR", input: `cidrContains("10.0.0.0/8", request.mapped)`, expect: true},
+		{desc: "IPv4 address in mapped CIDR", input: `cidrContains("::ffff:10.0.0.0/104", request.v4)`, expect: true},
+		{desc: "non canonical CIDR", input: `cidrContains("10.9.9.9/8", request.v4)`, expect: true},
+		{desc: "IPv6", input: `cidrContains("fd00::/8", request.v6)`, expect: true},
+		{desc: "IPv6 CIDR and IPv4 address", input: `cidrContains("::/0", request.v4)`, expect: false},
+		{desc: "any of arguments", input: `inAnyCIDR(request.v4, "192.168.0.0/16", "10.0.0.0/8")`, expect: true},
+		{desc: "any of list", input: `inAnyCIDR(request.public, request.networks)`, expect: false},
+		{desc: "none", input: `inAnyCIDR(request.v4)`, expect: false},
+		{desc: "private", input: `isPrivate(request.mapped) && isPrivate(request.v6)`, expect: true},
+		{desc: "public", input: `isPrivate(request.public)`, expect: false},
+		{desc: "version 4", input: `ipVersion(request.mapped)`, expect: 4},
+		{desc: "version 6", input: `ipVersion("::1")`, expect: 6},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			require.Equal(t, tc.expect, parseAndEval(t, p, tc.input))
+		})
+	}
+
+	for _, tc := range []struct {
+		input         string
+		expectMessage string
+	}{
+		{input: `cidrContains("10.0.0.0/33", request.v4)`, expectMessage: "1:14: invalid CIDR"},
+		{input: `inAnyCIDR(request.v4, "10.0.0.0/8", "bad")`, expectMessage: "1:37: invalid CIDR"},
+		{input: `cidrContains("::ffff:0:0/64", request.v4)`, expectMessage: "1:14: CIDR"},
+		{input: `isPrivate("10.0.0")`, expectMessage: "1:11: invalid IP address"},
+		{input: `isPrivate(request.invalid)`, expectMessage: "invalid IP address"},
+		{input: `ipVersion(1)`, expectMessage: "expected IP address"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := p.Parse(tc.input)
+			require.True(t, trace.IsBadParameter(err), "unexpected error %v", err)
+			require.Contains(t, err.Error(), tc.expectMessage)
+		})
+	}
+}
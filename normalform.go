@@ -0,0 +1,310 @@
+package predicate
+
+import (
+	"go/ast"
+	"go/token"
+
+	"github.com/gravitational/trace"
+	"github.com/vulcand/predicate/internal/astutil"
+)
+
+const (
+	// DefaultMaxClauses is the default limit of clauses in CNF and DNF.
+	DefaultMaxClauses = 1024
+)
+
+// NormalFormOptions limits the size of normal forms.
+type NormalFormOptions struct {
+	// MaxClauses is the maximum number of clauses, conversions of bigger
+	// expressions fail with trace.LimitExceeded, as CNF and DNF can be
+	// exponentially larger than the expression.
+	MaxClauses int
+}
+
+// CheckAndSetDefaults checks and sets default values.
+func (o *NormalFormOptions) CheckAndSetDefaults() error {
+	if o.MaxClauses < 0 {
+		return trace.BadParameter("clause limit can not be negative")
+	}
+	if o.MaxClauses == 0 {
+		o.MaxClauses = DefaultMaxClauses
+	}
+	return nil
+}
+
+// NormalForm is an expression in a normal form.
+type NormalForm struct {
+	// Expr is the expression in the normal form.
+	Expr ast.Expr
+	// Clauses are the operands of the outermost operator of CNF and DNF,
+	// each a list of literals, i.e. atoms and negated atoms. CNF clauses
+	// are joined by || and DNF clauses by &&. An empty clause list is true
+	// in CNF and false in DNF, an empty clause is false in CNF and true in
+	// DNF. Clauses are nil for NNF.
+	Clauses [][]ast.Expr
+}
+
+// String returns the expression as text.
+func (n NormalForm) String() string {
+	return astutil.ExprString(n.Expr)
+}
+
+// NNF returns the expression in negation normal form, where ! only applies
+// to atoms, e.g. !(a && x < 1) is !a || x >= 1 if Def.Inverses maps < to >=.
+// Atoms are all expressions other than &&, || and !, negations of true and
+// false are folded. expr is not modified.
+func (d Def) NNF(expr ast.Expr) (NormalForm, error) {
+	n := &normalizer{d: d}
+	out, err := n.nnf(expr, false)
+	if err != nil {
+		return NormalForm{}, trace.Wrap(err)
+	}
+	return NormalForm{Expr: out}, nil
+}
+
+// CNF returns the expression in conjunctive normal form, a && of clauses
+// that are || of literals, e.g. a && (b || c) for (a && b) || (a && c).
+// Repeated literals and clauses are removed.
+func (d Def) CNF(expr ast.Expr, opts NormalFormOptions) (NormalForm, error) {
+	return d.clausalForm(expr, opts, token.LAND)
+}
+
+// DNF returns the expression in disjunctive normal form, a || of clauses
+// that are && of literals, e.g. a && b || a && c for a && (b || c).
+// Repeated literals and clauses are removed.
+func (d Def) DNF(expr ast.Expr, opts NormalFormOptions) (NormalForm, error) {
+	return d.clausalForm(expr, opts, token.LOR)
+}
+
+// clausalForm returns CNF if outer is && and DNF if outer is ||.
+func (d Def) clausalForm(expr ast.Expr, opts NormalFormOptions, outer token.Token) (NormalForm, error) {
+	if err := opts.CheckAndSetDefaults(); err != nil {
+		return NormalForm{}, trace.Wrap(err)
+	}
+	n := &normalizer{d: d, maxClauses: opts.MaxClauses}
+	nnf, err := n.nnf(expr, false)
+	if err != nil {
+		return NormalForm{}, trace.Wrap(err)
+	}
+	clauses, err := n.clauses(nnf, outer)
+	if err != nil {
+		return NormalForm{}, trace.Wrap(err)
+	}
+	return NormalForm{Expr: clausesExpr(clauses, outer), Clauses: clauses}, nil
+}
+
+type normalizer struct {
+	d          Def
+	maxClauses int
+}
+
+// nnf pushes negations down to atoms, negate is true under an odd number of !.
+func (n *normalizer) nnf(expr ast.Expr, negate bool) (ast.Expr, error) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return n.nnf(e.X, negate)
+
+	case *ast.UnaryExpr:
+		if e.Op == token.NOT {
+			return n.nnf(e.X, !negate)
+		}
+
+	case *ast.BinaryExpr:
+		if e.Op == token.LAND || e.Op == token.LOR {
+			x, err := n.nnf(e.X, negate)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			y, err := n.nnf(e.Y, negate)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			op := e.Op
+			if negate {
+				op = dualOp(op)
+			}
+			return &ast.BinaryExpr{X: x, OpPos: e.OpPos, Op: op, Y: y}, nil
+		}
+	}
+	return n.literal(expr, negate)
+}
+
+// literal returns the atom or its negation, using inverses if possible.
+func (n *normalizer) literal(expr ast.Expr, negate bool) (ast.Expr, error) {
+	if b, ok := boolConst(expr); ok {
+		return boolIdent(b != negate), nil
+	}
+	if !negate {
+		return astutil.Unparen(expr), nil
+	}
+	switch e := astutil.Unparen(expr).(type) {
+	case *ast.BinaryExpr:
+		inverse, ok := n.inverse(e.Op.String())
+		if !ok {
+			break
+		}
+		op, ok := comparisonTokens[inverse]
+		if !ok {
+			return nil, trace.BadParameter("inverse %q of %v is not a comparison operator", inverse, e.Op)
+		}
+		return &ast.BinaryExpr{X: e.X, OpPos: e.OpPos, Op: op, Y: e.Y}, nil
+	case *ast.CallExpr:
+		id, ok := e.Fun.(*ast.Ident)
+		if !ok {
+			break
+		}
+		inverse, ok := n.inverse(id.Name)
+		if !ok {
+			break
+		}
+		return &ast.CallExpr{Fun: &ast.Ident{NamePos: id.NamePos, Name: inverse}, Lparen: e.Lparen, Args: e.Args, Rparen: e.Rparen}, nil
+	}
+	return &ast.UnaryExpr{Op: token.NOT, X: expr}, nil
+}
+
+// inverse returns the inverse of the operator or the function in either
+// direction of Def.Inverses.
+func (n *normalizer) inverse(name string) (string, bool) {
+	if inverse, ok := n.d.Inverses[name]; ok {
+		return inverse, true
+	}
+	for from, to := range n.d.Inverses {
+		if to == name {
+			return from, true
+		}
+	}
+	return "", false
+}
+
+// comparisonTokens are the operators that can be inverses.
+var comparisonTokens = map[string]token.Token{
+	token.EQL.String(): token.EQL,
+	token.NEQ.String(): token.NEQ,
+	token.LSS.String(): token.LSS,
+	token.LEQ.String(): token.LEQ,
+	token.GTR.String(): token.GTR,
+	token.GEQ.String(): token.GEQ,
+}
+
+func dualOp(op token.Token) token.Token {
+	if op == token.LAND {
+		return token.LOR
+	}
+	return token.LAND
+}
+
+// clauses returns clauses of the expression in NNF, joined by outer,
+// with literals joined by the dual operator.
+func (n *normalizer) clauses(expr ast.Expr, outer token.Token) ([][]ast.Expr, error) {
+	if b, ok := boolConst(expr); ok {
+		// In DNF true is a single empty clause, and false has no clauses,
+		// CNF is the other way around.
+		if b == (outer == token.LOR) {
+			return [][]ast.Expr{{}}, nil
+		}
+		return [][]ast.Expr{}, nil
+	}
+	bin, ok := astutil.Unparen(expr).(*ast.BinaryExpr)
+	if !ok || (bin.Op != token.LAND && bin.Op != token.LOR) {
+		return [][]ast.Expr{{expr}}, nil
+	}
+	x, err := n.clauses(bin.X, outer)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	y, err := n.clauses(bin.Y, outer)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if bin.Op == outer {
+		all := append(x, y...)
+		for _, clause := range all {
+			// An empty clause decides the result, it is true in DNF
+			// and false in CNF.
+			if len(clause) == 0 {
+				return [][]ast.Expr{{}}, nil
+			}
+		}
+		return n.limit(dedupeClauses(all))
+	}
+	// Distribute, e.g. (a || b) && (c || d) in DNF is
+	// a && c || a && d || b && c || b && d.
+	if len(x)*len(y) > n.maxClauses {
+		return nil, trace.LimitExceeded("normal form exceeds %v clauses", n.maxClauses)
+	}
+	out := make([][]ast.Expr, 0, len(x)*len(y))
+	for _, cx := range x {
+		for _, cy := range y {
+			clause := append(append([]ast.Expr{}, cx...), cy...)
+			out = append(out, dedupeLiterals(clause))
+		}
+	}
+	return n.limit(dedupeClauses(out))
+}
+
+func (n *normalizer) limit(clauses [][]ast.Expr) ([][]ast.Expr, error) {
+	if len(clauses) > n.maxClauses {
+		return nil, trace.LimitExceeded("normal form exceeds %v clauses", n.maxClauses)
+	}
+	return clauses, nil
+}
+
+// dedupeLiterals removes repeated literals keeping the first ones.
+func dedupeLiterals(clause []ast.Expr) []ast.Expr {
+	out := clause[:0]
+	seen := make(map[string]struct{}, len(clause))
+	for _, lit := range clause {
+		key := astutil.ExprString(lit)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, lit)
+	}
+	return out
+}
+
+// dedupeClauses removes repeated clauses keeping the first ones.
+func dedupeClauses(clauses [][]ast.Expr) [][]ast.Expr {
+	out := clauses[:0]
+	seen := make(map[string]struct{}, len(clauses))
+	for _, clause := range clauses {
+		key := astutil.ExprString(joinExprs(clause, token.LAND))
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, clause)
+	}
+	return out
+}
+
+// clausesExpr joins literals of clauses with the dual of outer,
+// and clauses with outer.
+func clausesExpr(clauses [][]ast.Expr, outer token.Token) ast.Expr {
+	if len(clauses) == 0 {
+		return boolIdent(outer == token.LAND)
+	}
+	exprs := make([]ast.Expr, len(clauses))
+	for i, clause := range clauses {
+		if len(clause) == 0 {
+			exprs[i] = boolIdent(outer == token.LOR)
+			continue
+		}
+		exprs[i] = joinExprs(clause, dualOp(outer))
+	}
+	return joinExprs(exprs, outer)
+}
+
+// joinExprs joins expressions with the operator, an empty list is
+// true for && and false for ||.
+func joinExprs(exprs []ast.Expr, op token.Token) ast.Expr {
+	if len(exprs) == 0 {
+		return boolIdent(op == token.LAND)
+	}
+	out := exprs[0]
+	for _, y := range exprs[1:] {
+		out = &ast.BinaryExpr{X: out, Op: op, Y: y}
+	}
+	return out
+}
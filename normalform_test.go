@@ -0,0 +1,157 @@
+package predicate
+
+import (
+	"go/parser"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+	"github.com/vulcand/predicate/internal/astutil"
+)
+
+func TestNormalForms(t *testing.T) {
+	t.Parallel()
+
+	d := Def{
+		Inverses: map[string]string{
+			"<":      ">=",
+			">":      "<=",
+			"==":     "!=",
+			"equals": "notEquals",
+		},
+	}
+	tests := []struct {
+		in  string
+		nnf string
+		cnf string
+		dnf string
+	}{
+		{
+			in:  `a`,
+			nnf: `a`,
+			cnf: `a`,
+			dnf: `a`,
+		},
+		{
+			in:  `!(a && x < 1)`,
+			nnf: `!a || x >= 1`,
+			cnf: `!a || x >= 1`,
+			dnf: `!a || x >= 1`,
+		},
+		{
+			in:  `!(x >= 1 || !(y <= 2))`,
+			nnf: `x < 1 && y <= 2`,
+			cnf: `x < 1 && y <= 2`,
+			dnf: `x < 1 && y <= 2`,
+		},
+		{
+			in:  `!(env == "prod") && !equals(a, b) && !notEquals(c, d) && !contains(x, y)`,
+			nnf: `env != "prod" && notEquals(a, b) && equals(c, d) && !contains(x, y)`,
+			cnf: `env != "prod" && notEquals(a, b) && equals(c, d) && !contains(x, y)`,
+			dnf: `env != "prod" && notEquals(a, b) && equals(c, d) && !contains(x, y)`,
+		},
+		{
+			in:  `a && (b || c)`,
+			nnf: `a && (b || c)`,
+			cnf: `a && (b || c)`,
+			dnf: `a && b || a && c`,
+		},
+		{
+			in:  `(a || b) && (c || d)`,
+			nnf: `(a || b) && (c || d)`,
+			cnf: `(a || b) && (c || d)`,
+			dnf: `a && c || a && d || b && c || b && d`,
+		},
+		{
+			in:  `a && b || a && c`,
+			nnf: `a && b || a && c`,
+			cnf: `a && (a || c) && (b || a) && (b || c)`,
+			dnf: `a && b || a && c`,
+		},
+		{
+			in:  `(a || a) && (a || b) && (a || a)`,
+			nnf: `(a || a) && (a || b) && (a || a)`,
+			cnf: `a && (a || b)`,
+			dnf: `a || a && b`,
+		},
+		{
+			in:  `!true || a`,
+			nnf: `false || a`,
+			cnf: `a`,
+			dnf: `a`,
+		},
+		{
+			in:  `a || !false`,
+			nnf: `a || true`,
+			cnf: `true`,
+			dnf: `true`,
+		},
+		{
+			in:  `a && false`,
+			nnf: `a && false`,
+			cnf: `false`,
+			dnf: `false`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			expr, err := parser.ParseExpr(tt.in)
+			require.NoError(t, err)
+
+			nnf, err := d.NNF(expr)
+			require.NoError(t, err)
+			require.Equal(t, tt.nnf, nnf.String())
+			require.Nil(t, nnf.Clauses)
+
+			cnf, err := d.CNF(expr, NormalFormOptions{})
+			require.NoError(t, err)
+			require.Equal(t, tt.cnf, cnf.String())
+
+			dnf, err := d.DNF(expr, NormalFormOptions{})
+			require.NoError(t, err)
+			require.Equal(t, tt.dnf, dnf.String())
+		})
+	}
+}
+
+func TestNormalFormClauses(t *testing.T) {
+	t.Parallel()
+
+	expr, err := parser.ParseExpr(`a && (b || !c)`)
+	require.NoError(t, err)
+	dnf, err := Def{}.DNF(expr, NormalFormOptions{})
+	require.NoError(t, err)
+	var clauses [][]string
+	for _, clause := range dnf.Clauses {
+		var lits []string
+		for _, lit := range clause {
+			lits = append(lits, astutil.ExprString(lit))
+		}
+		clauses = append(clauses, lits)
+	}
+	require.Equal(t, [][]string{{"a", "b"}, {"a", "!c"}}, clauses)
+}
+
+func TestNormalFormLimit(t *testing.T) {
+	t.Parallel()
+
+	// DNF of n conjunctions of pairs has 2^n clauses.
+	expr, err := parser.ParseExpr(`(a1 || b1) && (a2 || b2) && (a3 || b3) && (a4 || b4)`)
+	require.NoError(t, err)
+
+	_, err = Def{}.DNF(expr, NormalFormOptions{MaxClauses: 8})
+	require.True(t, trace.IsLimitExceeded(err), "unexpected error %v", err)
+
+	dnf, err := Def{}.DNF(expr, NormalFormOptions{MaxClauses: 16})
+	require.NoError(t, err)
+	require.Len(t, dnf.Clauses, 16)
+
+	cnf, err := Def{}.CNF(expr, NormalFormOptions{MaxClauses: 8})
+	require.NoError(t, err)
+	require.Len(t, cnf.Clauses, 4)
+
+	expr, err = parser.ParseExpr(`!(a < b)`)
+	require.NoError(t, err)
+	_, err = Def{Inverses: map[string]string{"<": "contains"}}.NNF(expr)
+	require.True(t, trace.IsBadParameter(err), "unexpected error %v", err)
+}
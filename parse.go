@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/gravitational/trace"
+	"github.com/vulcand/predicate/internal/astutil"
 )
 
 func NewParser(d Def) (Parser, error) {
@@ -26,7 +27,20 @@ func (p *predicateParser) Parse(in string) (any, error) {
 		return nil, err
 	}
 
-	return p.parse(expr)
+	if err := p.d.checkCost(expr); err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	val, err := p.parse(expr)
+	if err != nil {
+		return nil, withPosition(in, err)
+	}
+	if p.d.ThreeValued {
+		if t, ok := toTri(val); ok {
+			return t, nil
+		}
+	}
+	return val, nil
 }
 
 func (p *predicateParser) parse(expr ast.Expr) (any, error) {
@@ -57,10 +71,13 @@ func (p *predicateParser) parse(expr ast.Expr) (any, error) {
 
 	case *ast.Ident:
 		if p.d.GetIdentifier == nil {
-			return nil, trace.NotFound("%v is not defined", n.Name)
+			return p.unknownIdentifier(n, trace.NotFound("%v is not defined", n.Name))
 		}
 		val, err := p.d.GetIdentifier([]string{n.Name})
-		return val, trace.Wrap(err)
+		if err != nil {
+			return p.unknownIdentifier(n, err)
+		}
+		return val, nil
 
 	case *ast.CallExpr:
 		val, err := p.parseCallExpr(n)
@@ -82,11 +99,19 @@ func (p *predicateParser) parseBinaryExpr(expr *ast.BinaryExpr) (any, error) {
 		return nil, err
 	}
 
+	if val, ok, err := p.joinTri(expr.Op, x, y); ok {
+		return val, trace.Wrap(err)
+	}
+
 	val, err := p.joinPredicates(expr.Op, x, y)
 	return val, trace.Wrap(err)
 }
 
 func (p *predicateParser) parseUnaryExpr(expr *ast.UnaryExpr) (any, error) {
+	if p.d.ThreeValued {
+		return p.parseUnaryTri(expr)
+	}
+
 	joinFn, err := p.getJoinFunction(expr.Op)
 	if err != nil {
 		return nil, err
@@ -116,9 +141,13 @@ func (p *predicateParser) parseIndexExpr(expr *ast.IndexExpr) (any, error) {
 		return nil, trace.Wrap(err)
 	}
 
+	if p.d.ThreeValued && (isUnknown(mapVal) || isUnknown(keyVal)) {
+		return unknown{}, nil
+	}
+
 	val, err := p.d.GetProperty(mapVal, keyVal)
 	if err != nil {
-		return nil, trace.Wrap(err)
+		return p.unknownIdentifier(expr, err)
 	}
 
 	return val, nil
@@ -143,12 +172,12 @@ func (p *predicateParser) parseSelectorExpr(expr *ast.SelectorExpr) (any, error)
 	}
 
 	if p.d.GetIdentifier == nil {
-		return nil, trace.NotFound("%v is not defined", strings.Join(fields, "."))
+		return p.unknownIdentifier(expr, trace.NotFound("%v is not defined", strings.Join(fields, ".")))
 	}
 
 	val, err := p.d.GetIdentifier(fields)
 	if err != nil {
-		return nil, trace.Wrap(err)
+		return p.unknownIdentifier(expr, err)
 	}
 	return val, nil
 }
@@ -171,7 +200,14 @@ func evaluateSelector(sel *ast.SelectorExpr, fields []string) ([]string, error)
 }
 
 func (p *predicateParser) parseCallExpr(expr *ast.CallExpr) (any, error) {
-	fn, args, err := p.getFunctionAndArgs(expr)
+	name, fn, args, err := p.getFunctionAndArgs(expr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	// Literals are prepared first, so invalid ones are reported
+	// even if evaluating other arguments fails.
+	prepared, err := p.prepareLiterals(name, fn, args)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -181,10 +217,51 @@ func (p *predicateParser) parseCallExpr(expr *ast.CallExpr) (any, error) {
 		return nil, err
 	}
 
+	if p.d.ThreeValued && anyUnknown(arguments) {
+		return unknown{}, nil
+	}
+
+	for i, val := range prepared {
+		arguments[i] = val
+	}
 	val, err := callFunction(fn, arguments)
 	return val, trace.Wrap(err)
 }
 
+// prepareLiterals returns values of literal arguments prepared by
+// FunctionInfo.Literals of the function, e.g. compiled regular expressions,
+// keyed by argument index.
+func (p *predicateParser) prepareLiterals(name string, fn any, nodes []ast.Expr) (map[int]any, error) {
+	info, ok := p.d.FunctionInfo[name]
+	if !ok || len(info.Literals) == 0 {
+		return nil, nil
+	}
+	prepared := make(map[int]any)
+	fnType := reflect.TypeOf(fn)
+	variadic := fnType != nil && fnType.Kind() == reflect.Func && fnType.IsVariadic()
+	for i, node := range nodes {
+		lit, ok := astutil.Unparen(node).(*ast.BasicLit)
+		if !ok {
+			continue
+		}
+		prepare := info.literal(i, variadic)
+		if prepare == nil {
+			continue
+		}
+		value, err := literalToValue(lit)
+		if err != nil {
+			// Invalid literals are reported when they are evaluated.
+			continue
+		}
+		val, err := prepare(value)
+		if err != nil {
+			return nil, &nodeError{node: lit, err: err}
+		}
+		prepared[i] = val
+	}
+	return prepared, nil
+}
+
 func (p *predicateParser) getFunction(name string) (any, error) {
 	v, ok := p.d.Functions[name]
 	if !ok {
@@ -230,12 +307,12 @@ func (p *predicateParser) getJoinFunction(op token.Token) (any, error) {
 	return fn, nil
 }
 
-func (p *predicateParser) getFunctionAndArgs(callExpr *ast.CallExpr) (any, []ast.Expr, error) {
+func (p *predicateParser) getFunctionAndArgs(callExpr *ast.CallExpr) (string, any, []ast.Expr, error) {
 	switch f := callExpr.Fun.(type) {
 	case *ast.Ident:
 		// Plain function with a single identifier name.
 		fn, err := p.getFunction(f.Name)
-		return fn, callExpr.Args, trace.Wrap(err)
+		return f.Name, fn, callExpr.Args, trace.Wrap(err)
 	case *ast.SelectorExpr:
 		// This is a selector like number.DivisibleBy(2) or set("a", "b").contains("b")
 
@@ -245,19 +322,19 @@ func (p *predicateParser) getFunctionAndArgs(callExpr *ast.CallExpr) (any, []ast
 			// Pass the method receiver as the first arg, it will first be
 			// evaluated with the rest of the arguments
 			args := append([]ast.Expr{f.X}, callExpr.Args...)
-			return method, args, nil
+			return f.Sel.Name, method, args, nil
 		}
 
 		// If this isn't a method, it may be a module function like "number.DivisibleBy"
 		id, okIdent := f.X.(*ast.Ident)
 		if !okIdent {
-			return nil, nil, trace.BadParameter("expected selector identifier, got: %T", f.X)
+			return "", nil, nil, trace.BadParameter("expected selector identifier, got: %T", f.X)
 		}
 		fnName := fmt.Sprintf("%s.%s", id.Name, f.Sel.Name)
 		fn, err := p.getFunction(fnName)
-		return fn, callExpr.Args, trace.Wrap(err)
+		return fnName, fn, callExpr.Args, trace.Wrap(err)
 	default:
-		return nil, nil, trace.BadParameter("unknown function type %T", f)
+		return "", nil, nil, trace.BadParameter("unknown function type %T", f)
 	}
 }
 
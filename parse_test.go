@@ -572,6 +572,33 @@ func (s *PredicateSuite) TestGetTagField() {
 	}
 }
 
+type testShadowedInner struct {
+	Spec struct {
+		Other string `json:"other"`
+	} `json:"spec"`
+}
+
+type testShadowingOuter struct {
+	testShadowedInner
+	Spec struct {
+		Name string `json:"name"`
+	} `json:"spec"`
+}
+
+func (s *PredicateSuite) TestGetTagFieldShadowed() {
+	val := testShadowingOuter{}
+	val.Spec.Name = "outer"
+	val.testShadowedInner.Spec.Other = "inner"
+
+	// Fields of the outer struct shadow fields of embedded structs.
+	out, err := GetFieldByTag(val, "json", []string{"spec", "name"})
+	s.NoError(err)
+	s.Equal("outer", out)
+
+	_, err = GetFieldByTag(val, "json", []string{"spec", "other"})
+	s.True(trace.IsNotFound(err), "unexpected error %v", err)
+}
+
 func (s *PredicateSuite) TestUnhappyCases() {
 	cases := []string{
 		")(",                      // invalid expression
@@ -776,3 +803,291 @@ func TestNestedExpressions(t *testing.T) {
 		})
 	}
 }
+
+type testRule struct {
+	Name   string   `json:"name"`
+	Logins []string `json:"logins"`
+}
+
+type testSpec struct {
+	Labels map[string]string `json:"labels"`
+	Rules  []testRule        `json:"rules"`
+	Owner  testOwner         `json:"owner"`
+}
+
+type testOwner interface {
+	GetDisplayName() string
+}
+
+type testUser struct {
+	displayName string
+}
+
+func (u *testUser) GetDisplayName() string {
+	return u.displayName
+}
+
+type testResource struct {
+	Spec testSpec `json:"spec"`
+}
+
+func (r testResource) GetKind() (string, error) {
+	return "", trace.BadParameter("kind is not set")
+}
+
+func (s testSpec) GetVersion() string {
+	panic("version is not set")
+}
+
+func TestGetFieldByTagWithOptions(t *testing.T) {
+	t.Parallel()
+
+	val := &testResource{
+		Spec: testSpec{
+			Labels: map[string]string{"env": "prod"},
+			Rules:  []testRule{{Name: "a", Logins: []string{"root"}}, {Name: "b"}},
+			Owner:  &testUser{displayName: "alice"},
+		},
+	}
+	all := FieldOptions{Maps: true, Slices: true, Getters: true}
+
+	for _, tc := range []struct {
+		desc        string
+		fields      []string
+		opts        FieldOptions
+		expect      any
+		expectError func(error) bool
+	}{
+		{
+			desc:   "map value",
+			fields: []string{"spec", "labels", "env"},
+			opts:   FieldOptions{Maps: true},
+			expect: "prod",
+		},
+		{
+			desc:        "maps disabled",
+			fields:      []string{"spec", "labels", "env"},
+			expectError: trace.IsNotFound,
+		},
+		{
+			desc:        "missing map key",
+			fields:      []string{"spec", "labels", "team"},
+			opts:        all,
+			expectError: trace.IsNotFound,
+		},
+		{
+			desc:   "slice element field",
+			fields: []string{"spec", "rules", "1", "name"},
+			opts:   FieldOptions{Slices: true},
+			expect: "b",
+		},
+		{
+			desc:   "nested slices",
+			fields: []string{"spec", "rules", "0", "logins", "0"},
+			opts:   FieldOptions{Slices: true},
+			expect: "root",
+		},
+		{
+			desc:        "slices disabled",
+			fields:      []string{"spec", "rules", "0", "name"},
+			expectError: trace.IsNotFound,
+		},
+		{
+			desc:        "index out of range",
+			fields:      []string{"spec", "rules", "2", "name"},
+			opts:        all,
+			expectError: trace.IsNotFound,
+		},
+		{
+			desc:        "not an index",
+			fields:      []string{"spec", "rules", "first", "name"},
+			opts:        all,
+			expectError: trace.IsNotFound,
+		},
+		{
+			desc:   "getter on interface",
+			fields: []string{"spec", "owner", "display_name"},
+			opts:   FieldOptions{Getters: true},
+			expect: "alice",
+		},
+		{
+			desc:   "getter with camel case name",
+			fields: []string{"spec", "owner", "displayName"},
+			opts:   FieldOptions{Getters: true},
+			expect: "alice",
+		},
+		{
+			desc:        "getters disabled",
+			fields:      []string{"spec", "owner", "display_name"},
+			expectError: trace.IsNotFound,
+		},
+		{
+			desc:        "getter error",
+			fields:      []string{"kind"},
+			opts:        all,
+			expectError: trace.IsBadParameter,
+		},
+		{
+			desc:        "getter panic",
+			fields:      []string{"spec", "version"},
+			opts:        all,
+			expectError: trace.IsBadParameter,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			out, err := GetFieldByTagWithOptions(val, "json", tc.fields, tc.opts)
+			if tc.expectError != nil {
+				require.True(t, tc.expectError(err), "unexpected error %v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, out)
+		})
+	}
+}
+
+type testProtoMetadata struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name"`
+}
+
+type testProtoResource struct {
+	testProtoMetadata
+	DisplayName string `protobuf:"bytes,2,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	Title       string `yaml:"title"`
+	Description string
+	Alias       string `json:"description"`
+}
+
+type testLabels struct {
+	Label string
+}
+
+type testLabeled struct {
+	testLabels
+	Tag string `json:"label"`
+}
+
+func TestGetFieldByTags(t *testing.T) {
+	t.Parallel()
+
+	val := testProtoResource{
+		testProtoMetadata: testProtoMetadata{Name: "db"},
+		DisplayName:       "Database",
+		Title:             "Main database",
+		Description:       "go field",
+		Alias:             "json field",
+	}
+	tags := []string{"json", "yaml", ProtobufTag, FieldNameTag}
+
+	for _, tc := range []struct {
+		desc        string
+		val         any
+		tags        []string
+		fields      []string
+		strict      bool
+		expect      any
+		expectError func(error) bool
+	}{
+		{
+			desc:   "json tag",
+			tags:   tags,
+			fields: []string{"display_name"},
+			expect: "Database",
+		},
+		{
+			desc:   "protobuf json name",
+			tags:   tags,
+			fields: []string{"displayName"},
+			expect: "Database",
+		},
+		{
+			desc:   "protobuf name only",
+			tags:   []string{ProtobufTag},
+			fields: []string{"display_name"},
+			expect: "Database",
+		},
+		{
+			desc:   "yaml tag",
+			tags:   tags,
+			fields: []string{"title"},
+			expect: "Main database",
+		},
+		{
+			desc:   "go field name",
+			tags:   tags,
+			fields: []string{"Title"},
+			expect: "Main database",
+		},
+		{
+			desc:   "embedded protobuf struct",
+			tags:   tags,
+			fields: []string{"name"},
+			expect: "db",
+		},
+		{
+			desc:   "earlier tag wins",
+			tags:   tags,
+			fields: []string{"description"},
+			expect: "json field",
+		},
+		{
+			desc:        "ambiguous in strict mode",
+			tags:        tags,
+			fields:      []string{"description"},
+			strict:      true,
+			expectError: trace.IsBadParameter,
+		},
+		{
+			desc:   "same field by several tags in strict mode",
+			tags:   tags,
+			fields: []string{"display_name"},
+			strict: true,
+			expect: "Database",
+		},
+		{
+			desc:        "not found",
+			tags:        tags,
+			fields:      []string{"owner"},
+			expectError: trace.IsNotFound,
+		},
+		{
+			desc:   "embedded field matched by a later tag",
+			val:    testLabeled{testLabels: testLabels{Label: "embedded"}, Tag: "outer"},
+			tags:   []string{"json", FieldNameTag},
+			fields: []string{"label"},
+			expect: "outer",
+		},
+		{
+			desc:   "embedded field matched by the only tag",
+			val:    testLabeled{testLabels: testLabels{Label: "embedded"}, Tag: "outer"},
+			tags:   []string{FieldNameTag},
+			fields: []string{"label"},
+			expect: "embedded",
+		},
+		{
+			desc:        "empty tag does not match field names",
+			tags:        []string{""},
+			fields:      []string{"Title"},
+			expectError: trace.IsNotFound,
+		},
+		{
+			desc:        "missing tags",
+			fields:      []string{"name"},
+			expectError: trace.IsBadParameter,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			in := tc.val
+			if in == nil {
+				in = val
+			}
+			out, err := GetFieldByTags(in, tc.tags, tc.fields, FieldOptions{Strict: tc.strict})
+			if tc.expectError != nil {
+				require.True(t, tc.expectError(err), "unexpected error %v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, out)
+		})
+	}
+}
@@ -0,0 +1,379 @@
+package predicate
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gravitational/trace"
+	"github.com/vulcand/predicate/internal/astutil"
+)
+
+// Residual is the result of partial evaluation, either a constant
+// or the part of the expression that could not be evaluated.
+type Residual struct {
+	// Expr is the remaining expression, nil if the result is a constant.
+	Expr ast.Expr
+	// Value is the result if Expr is nil.
+	Value bool
+}
+
+// IsConst returns true if the expression was evaluated to a constant.
+func (r Residual) IsConst() bool {
+	return r.Expr == nil
+}
+
+// String returns the remaining expression or the constant, true or false.
+func (r Residual) String() string {
+	if r.Expr == nil {
+		return strconv.FormatBool(r.Value)
+	}
+	return astutil.ExprString(r.Expr)
+}
+
+// PartialEval evaluates the boolean expression as far as the known part of
+// the environment allows. Identifiers are unknown when GetIdentifier fails
+// with trace.NotFound, e.g. for rule
+//
+//	user.team == "sre" && resource.env == "prod"
+//
+// and an environment that only knows user.team = "sre", the residual is
+//
+//	resource.env == "prod"
+//
+// Known values are inlined in the residual as literals. PartialEval fails
+// if a known value the residual depends on can not be written as a literal,
+// e.g. a list or a negative number, as the residual would otherwise look
+// it up again. Identifiers true and false are constants unless GetIdentifier
+// defines them, the same way Parse treats them with Def.ThreeValued.
+// Logical operators are simplified using boolean results, bool, BoolPredicate
+// or func() bool, all other operators and functions are only called
+// when all of their arguments are known.
+func (d Def) PartialEval(in string) (Residual, error) {
+	expr, err := parser.ParseExpr(in)
+	if err != nil {
+		return Residual{}, err
+	}
+	if err := d.checkCost(expr); err != nil {
+		return Residual{}, trace.Wrap(err)
+	}
+	e := &partialEvaluator{p: &predicateParser{d: d}}
+	val, err := e.eval(expr)
+	if err != nil {
+		return Residual{}, withPosition(in, err)
+	}
+	if !val.known {
+		return Residual{Expr: val.expr}, nil
+	}
+	b, ok := toBool(val.value)
+	if !ok {
+		return Residual{}, trace.BadParameter("expression evaluates to %T, not a boolean", val.value)
+	}
+	return Residual{Value: b}, nil
+}
+
+// partialValue is a known value or a residual expression. Known values
+// keep the expression they were evaluated from, to be used in residuals
+// if the value can not be written as a literal.
+type partialValue struct {
+	known bool
+	value any
+	expr  ast.Expr
+}
+
+type partialEvaluator struct {
+	p *predicateParser
+}
+
+func (e *partialEvaluator) eval(expr ast.Expr) (partialValue, error) {
+	switch n := expr.(type) {
+	case *ast.ParenExpr:
+		return e.eval(n.X)
+
+	case *ast.BasicLit:
+		val, err := literalToValue(n)
+		if err != nil {
+			return partialValue{}, trace.Wrap(err)
+		}
+		return partialValue{known: true, value: val, expr: n}, nil
+
+	case *ast.Ident:
+		return e.identifier([]string{n.Name}, n)
+
+	case *ast.SelectorExpr:
+		fields, err := evaluateSelector(n, []string{})
+		if err != nil {
+			return partialValue{}, trace.Wrap(err)
+		}
+		return e.identifier(fields, n)
+
+	case *ast.IndexExpr:
+		return e.evalIndex(n)
+
+	case *ast.UnaryExpr:
+		return e.evalUnary(n)
+
+	case *ast.BinaryExpr:
+		return e.evalBinary(n)
+
+	case *ast.CallExpr:
+		return e.evalCall(n)
+
+	default:
+		return partialValue{}, trace.BadParameter("%T is not supported", expr)
+	}
+}
+
+func (e *partialEvaluator) identifier(selector []string, node ast.Expr) (partialValue, error) {
+	var val any
+	var err error = trace.NotFound("%v is not defined", strings.Join(selector, "."))
+	if e.p.d.GetIdentifier != nil {
+		val, err = e.p.d.GetIdentifier(selector)
+	}
+	if err == nil {
+		return partialValue{known: true, value: val, expr: node}, nil
+	}
+	if !trace.IsNotFound(err) {
+		return partialValue{}, trace.Wrap(err)
+	}
+	if id, ok := node.(*ast.Ident); ok && (id.Name == "true" || id.Name == "false") {
+		return partialValue{known: true, value: id.Name == "true", expr: node}, nil
+	}
+	return partialValue{expr: node}, nil
+}
+
+func (e *partialEvaluator) evalIndex(n *ast.IndexExpr) (partialValue, error) {
+	x, err := e.eval(n.X)
+	if err != nil {
+		return partialValue{}, trace.Wrap(err)
+	}
+	index, err := e.eval(n.Index)
+	if err != nil {
+		return partialValue{}, trace.Wrap(err)
+	}
+	if !x.known || !index.known {
+		exprs, err := residualExprs(x, index)
+		if err != nil {
+			return partialValue{}, trace.Wrap(err)
+		}
+		return partialValue{expr: &ast.IndexExpr{X: exprs[0], Lbrack: n.Lbrack, Index: exprs[1], Rbrack: n.Rbrack}}, nil
+	}
+	if e.p.d.GetProperty == nil {
+		return partialValue{}, trace.NotFound("properties are not supported")
+	}
+	val, err := e.p.d.GetProperty(x.value, index.value)
+	if err != nil {
+		return partialValue{}, trace.Wrap(err)
+	}
+	return partialValue{known: true, value: val, expr: n}, nil
+}
+
+func (e *partialEvaluator) evalUnary(n *ast.UnaryExpr) (partialValue, error) {
+	x, err := e.eval(n.X)
+	if err != nil {
+		return partialValue{}, trace.Wrap(err)
+	}
+	if !x.known {
+		return partialValue{expr: &ast.UnaryExpr{OpPos: n.OpPos, Op: n.Op, X: x.expr}}, nil
+	}
+	if b, ok := toBool(x.value); ok && n.Op == token.NOT {
+		return partialValue{known: true, value: !b, expr: n}, nil
+	}
+	joinFn, err := e.p.getJoinFunction(n.Op)
+	if err != nil {
+		return partialValue{}, trace.Wrap(err)
+	}
+	val, err := callFunction(joinFn, []any{x.value})
+	if err != nil {
+		return partialValue{}, trace.Wrap(err)
+	}
+	return partialValue{known: true, value: val, expr: n}, nil
+}
+
+func (e *partialEvaluator) evalBinary(n *ast.BinaryExpr) (partialValue, error) {
+	x, err := e.eval(n.X)
+	if err != nil {
+		return partialValue{}, trace.Wrap(err)
+	}
+	y, err := e.eval(n.Y)
+	if err != nil {
+		return partialValue{}, trace.Wrap(err)
+	}
+
+	if n.Op == token.LAND || n.Op == token.LOR {
+		if val, ok := simplifyLogical(n.Op, x, y); ok {
+			return val, nil
+		}
+	}
+
+	if !x.known || !y.known {
+		exprs, err := residualExprs(x, y)
+		if err != nil {
+			return partialValue{}, trace.Wrap(err)
+		}
+		return partialValue{expr: &ast.BinaryExpr{X: exprs[0], OpPos: n.OpPos, Op: n.Op, Y: exprs[1]}}, nil
+	}
+	val, err := e.p.joinPredicates(n.Op, x.value, y.value)
+	if err != nil {
+		return partialValue{}, trace.Wrap(err)
+	}
+	return partialValue{known: true, value: val, expr: n}, nil
+}
+
+// simplifyLogical simplifies && and || if either operand is a known boolean.
+func simplifyLogical(op token.Token, x, y partialValue) (partialValue, bool) {
+	xb, xok := knownBool(x)
+	yb, yok := knownBool(y)
+	switch {
+	case xok && yok:
+		if op == token.LAND {
+			return constValue(xb && yb), true
+		}
+		return constValue(xb || yb), true
+	case xok:
+		// true && y is y, false && y is false, true || y is true, false || y is y.
+		if xb == (op == token.LAND) {
+			return y, true
+		}
+		return constValue(xb), true
+	case yok:
+		if yb == (op == token.LAND) {
+			return x, true
+		}
+		return constValue(yb), true
+	default:
+		return partialValue{}, false
+	}
+}
+
+func knownBool(v partialValue) (bool, bool) {
+	if !v.known {
+		return false, false
+	}
+	return toBool(v.value)
+}
+
+func constValue(b bool) partialValue {
+	return partialValue{known: true, value: b, expr: ast.NewIdent(strconv.FormatBool(b))}
+}
+
+func (e *partialEvaluator) evalCall(n *ast.CallExpr) (partialValue, error) {
+	name, fn, args, err := e.p.getFunctionAndArgs(n)
+	if err != nil {
+		return partialValue{}, trace.Wrap(err)
+	}
+	prepared, err := e.p.prepareLiterals(name, fn, args)
+	if err != nil {
+		return partialValue{}, trace.Wrap(err)
+	}
+	vals := make([]partialValue, len(args))
+	known := true
+	for i, arg := range args {
+		vals[i], err = e.eval(arg)
+		if err != nil {
+			return partialValue{}, trace.Wrap(err)
+		}
+		known = known && vals[i].known
+	}
+
+	if !known {
+		exprs, err := residualExprs(vals...)
+		if err != nil {
+			return partialValue{}, trace.Wrap(err)
+		}
+		call := &ast.CallExpr{Fun: n.Fun, Lparen: n.Lparen, Args: exprs, Rparen: n.Rparen}
+		if len(args) != len(n.Args) {
+			// Methods get the receiver as the first argument.
+			sel := n.Fun.(*ast.SelectorExpr)
+			call.Fun = &ast.SelectorExpr{X: exprs[0], Sel: sel.Sel}
+			call.Args = exprs[1:]
+		}
+		return partialValue{expr: call}, nil
+	}
+
+	arguments := make([]any, len(vals))
+	for i := range vals {
+		arguments[i] = vals[i].value
+	}
+	for i, val := range prepared {
+		arguments[i] = val
+	}
+	val, err := callFunction(fn, arguments)
+	if err != nil {
+		return partialValue{}, trace.Wrap(err)
+	}
+	return partialValue{known: true, value: val, expr: n}, nil
+}
+
+// residualExprs returns the expressions to use for the values in a residual,
+// known values are written as literals at the positions of their expressions.
+func residualExprs(vals ...partialValue) ([]ast.Expr, error) {
+	out := make([]ast.Expr, len(vals))
+	for i, v := range vals {
+		if !v.known {
+			out[i] = v.expr
+			continue
+		}
+		lit, ok := valueToExpr(v.value, v.expr.Pos())
+		if !ok {
+			return nil, &nodeError{node: v.expr, err: trace.BadParameter(
+				"%v is known, but its value of type %T can not be written in the residual", astutil.ExprString(v.expr), v.value)}
+		}
+		out[i] = lit
+	}
+	return out, nil
+}
+
+// valueToExpr converts booleans, strings and numbers to literals at pos.
+// Integers are limited to the range strconv.Atoi reads literals in.
+func valueToExpr(v any, pos token.Pos) (ast.Expr, bool) {
+	if b, ok := toBool(v); ok {
+		return &ast.Ident{NamePos: pos, Name: strconv.FormatBool(b)}, true
+	}
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.String:
+		return &ast.BasicLit{ValuePos: pos, Kind: token.STRING, Value: strconv.Quote(val.String())}, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if val.Int() < 0 || val.Int() > math.MaxInt {
+			return nil, false
+		}
+		return &ast.BasicLit{ValuePos: pos, Kind: token.INT, Value: strconv.FormatInt(val.Int(), 10)}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if val.Uint() > math.MaxInt {
+			return nil, false
+		}
+		return &ast.BasicLit{ValuePos: pos, Kind: token.INT, Value: strconv.FormatUint(val.Uint(), 10)}, true
+	case reflect.Float32, reflect.Float64:
+		f := val.Float()
+		if f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, false
+		}
+		s := strconv.FormatFloat(f, 'g', -1, 64)
+		if _, err := strconv.Atoi(s); err == nil {
+			// Keep the literal a float, e.g. 4.0 and not 4.
+			s += ".0"
+		}
+		return &ast.BasicLit{ValuePos: pos, Kind: token.FLOAT, Value: s}, true
+	default:
+		return nil, false
+	}
+}
+
+// toBool converts booleans and boolean predicates to bool.
+func toBool(v any) (bool, bool) {
+	switch b := v.(type) {
+	case bool:
+		return b, true
+	case BoolPredicate:
+		return b(), true
+	case func() bool:
+		return b(), true
+	default:
+		return false, false
+	}
+}
@@ -0,0 +1,139 @@
+package predicate
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+	"github.com/vulcand/predicate/internal/astutil"
+)
+
+func TestPartialEval(t *testing.T) {
+	t.Parallel()
+
+	known := map[string]any{
+		"user.team":   "sre",
+		"user.level":  3,
+		"user.logins": []string{"root"},
+		"user.score":  2.0,
+		"user.id":     uint64(math.MaxUint64),
+	}
+	d, err := Def{
+		Operators: Operators{
+			AND: And,
+			OR:  Or,
+			NOT: Not,
+			EQ:  EqualValues,
+			NEQ: NotEqualValues,
+			GT:  Greater,
+			LT:  Less,
+		},
+		Functions: map[string]any{
+			"contains": Contains,
+			"fail": func() (any, error) {
+				return nil, trace.BadParameter("fail is called")
+			},
+		},
+		GetIdentifier: func(selector []string) (any, error) {
+			path := ""
+			for i, s := range selector {
+				if i > 0 {
+					path += "."
+				}
+				path += s
+			}
+			if v, ok := known[path]; ok {
+				return v, nil
+			}
+			if path == "user.broken" {
+				return nil, trace.AccessDenied("access denied")
+			}
+			return nil, trace.NotFound("%v is not found", path)
+		},
+		GetProperty: GetStringMapValue,
+	}.Merge(StringsModule())
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		input  string
+		expect string
+	}{
+		{input: `user.team == "sre" && resource.env == "prod"`, expect: `resource.env == "prod"`},
+		{input: `user.team == "dev" && resource.env == "prod"`, expect: `false`},
+		{input: `user.team == "sre" || resource.env == "prod"`, expect: `true`},
+		{input: `resource.env == "prod" || user.team == "dev"`, expect: `resource.env == "prod"`},
+		{input: `user.team == "sre" && user.level > 2`, expect: `true`},
+		{input: `!(user.team == "sre") || resource.env == "prod"`, expect: `resource.env == "prod"`},
+		{input: `resource.level < user.level`, expect: `resource.level < 3`},
+		{input: `resource.score == user.score`, expect: `resource.score == 2.0`},
+		{input: `resource.owner == upper(user.team)`, expect: `resource.owner == "SRE"`},
+		{input: `contains(user.logins, "root") && resource.env == "prod"`, expect: `resource.env == "prod"`},
+		{input: `resource.name.hasPrefix(user.team)`, expect: `resource.name.hasPrefix("sre")`},
+		{input: `resource.labels["env"] == "prod"`, expect: `resource.labels["env"] == "prod"`},
+		{input: `(a || b) && user.team == "sre"`, expect: `a || b`},
+		{input: `(a || b) && (c || user.team == "x")`, expect: `(a || b) && c`},
+		{input: `!resource.deleted && true`, expect: `!resource.deleted`},
+		{input: `resource.deleted || false`, expect: `resource.deleted`},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			r, err := d.PartialEval(tc.input)
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, r.String())
+			if r.IsConst() {
+				return
+			}
+			// The residual can be parsed back to the same tree.
+			parsed, err := parser.ParseExpr(r.String())
+			require.NoError(t, err)
+			require.Equal(t, r.String(), astutil.ExprString(parsed))
+		})
+	}
+
+	r, err := d.PartialEval(`user.team == "sre" && resource.env == "prod"`)
+	require.NoError(t, err)
+	bin, ok := r.Expr.(*ast.BinaryExpr)
+	require.True(t, ok)
+	require.Equal(t, "resource.env", astutil.ExprString(bin.X))
+	// Residual nodes keep the positions of the nodes they replace.
+	require.Equal(t, token.Pos(23), bin.Pos())
+	require.Equal(t, token.Pos(36), bin.OpPos)
+
+	r, err = d.PartialEval(`resource.level < user.level`)
+	require.NoError(t, err)
+	bin, ok = r.Expr.(*ast.BinaryExpr)
+	require.True(t, ok)
+	require.Equal(t, token.Pos(18), bin.Y.Pos())
+
+	for _, input := range []string{
+		`user.broken == "a" && resource.env == "prod"`,
+		`user.team`,
+		`fail() && resource.env == "prod"`,
+		`user.team == 1`,
+		`contains(user.logins, resource.login)`,
+		`user.logins.join(resource.separator) == "root"`,
+		`resource.id == user.id`,
+	} {
+		_, err := d.PartialEval(input)
+		require.Error(t, err, input)
+	}
+
+	// The residual never looks up known values again.
+	_, err = d.PartialEval(`contains(user.logins, resource.login)`)
+	require.True(t, trace.IsBadParameter(err), "unexpected error %v", err)
+	require.Contains(t, err.Error(), "1:10:")
+
+	// The cost limit applies.
+	limited := d
+	limited.MaxCost = 1
+	_, err = limited.PartialEval(`user.team == "sre" && resource.env == "prod"`)
+	require.True(t, trace.IsLimitExceeded(err), "unexpected error %v", err)
+
+	// true and false are constants without GetIdentifier, as in Parse.
+	r, err = Def{Operators: Operators{AND: And, OR: Or}}.PartialEval(`true && false`)
+	require.NoError(t, err)
+	require.Equal(t, "false", r.String())
+}
@@ -0,0 +1,91 @@
+package predicate
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"github.com/gravitational/trace"
+	"github.com/vulcand/predicate/internal/astutil"
+)
+
+// Position is a position in the expression text.
+type Position struct {
+	// Offset is the byte offset, starting at 0.
+	Offset int
+	// Line is the line number, starting at 1.
+	Line int
+	// Column is the byte offset in the line, starting at 1.
+	Column int
+}
+
+// String returns position in line:column format.
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Span is a range of the expression text.
+type Span struct {
+	// Start is the position of the first character.
+	Start Position
+	// End is the position right after the last character.
+	End Position
+}
+
+// String returns the start position of the span.
+func (s Span) String() string {
+	return s.Start.String()
+}
+
+// positionOf converts a position of a node returned by parser.ParseExpr
+// to the position in the expression text.
+func positionOf(in string, pos token.Pos) Position {
+	return Position(astutil.PositionOf(in, pos))
+}
+
+// spanOf returns the span of the node of the expression parsed from in
+// by parser.ParseExpr.
+func spanOf(in string, node ast.Node) Span {
+	return Span{Start: positionOf(in, node.Pos()), End: positionOf(in, node.End())}
+}
+
+// nodeError is an error caused by a particular node of the expression,
+// Parse reports it together with the node position.
+type nodeError struct {
+	node ast.Node
+	err  error
+}
+
+func (e *nodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *nodeError) Unwrap() error {
+	return e.err
+}
+
+// withPosition adds the node position to errors caused by a node, keeping
+// the kind of the error, e.g. trace.LimitExceeded for regex limits.
+func withPosition(in string, err error) error {
+	var ne *nodeError
+	if !errors.As(err, &ne) {
+		return err
+	}
+	return errorOfKind(ne.err, fmt.Sprintf("%v: %v", positionOf(in, ne.node.Pos()), ne.err))
+}
+
+// errorOfKind returns a new error with the message and the kind of err,
+// LimitExceeded, NotFound or AccessDenied, and BadParameter otherwise.
+func errorOfKind(err error, msg string) error {
+	switch {
+	case trace.IsLimitExceeded(err):
+		return trace.LimitExceeded("%s", msg)
+	case trace.IsNotFound(err):
+		return trace.NotFound("%s", msg)
+	case trace.IsAccessDenied(err):
+		return trace.AccessDenied("%s", msg)
+	default:
+		return trace.BadParameter("%s", msg)
+	}
+}
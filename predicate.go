@@ -60,6 +60,12 @@ Here's an example of fully functional predicate language to deal with division r
 */
 package predicate
 
+import (
+	"reflect"
+
+	"github.com/gravitational/trace"
+)
+
 // Def defines parser context including supported operators, functions, methods,
 // identifiers, and property accessors.
 type Def struct {
@@ -73,8 +79,65 @@ type Def struct {
 	GetIdentifier GetIdentifierFn
 	// GetProperty returns property from a map
 	GetProperty GetPropertyFn
+	// FunctionInfo holds optional information about functions and methods,
+	// keyed by the names used in Functions and Methods. Costs of operators
+	// are keyed by the operator, e.g. "==".
+	FunctionInfo map[string]FunctionInfo
+	// ThreeValued makes identifiers and properties that fail with
+	// trace.NotFound unknown instead of failing Parse. Functions and
+	// operators with unknown arguments return unknown without being
+	// called, and &&, || and ! follow Kleene logic using AndTri, OrTri
+	// and NotTri. Parse then returns TriPredicate for boolean results,
+	// so callers can decide whether unknown fails open or closed.
+	ThreeValued bool
+	// Inverses maps comparison operators and functions to their negations,
+	// e.g. "<" to ">=" or "equals" to "notEquals", so normal forms can push
+	// ! through them. Inverses work both ways, only one direction is needed.
+	Inverses map[string]string
+	// MaxCost makes Parse refuse expressions with a worst-case cost over
+	// the limit with trace.LimitExceeded, see EstimateCost. Zero means
+	// no limit.
+	MaxCost float64
+	// CostOptions configures the cost estimation used for MaxCost.
+	CostOptions CostOptions
+}
+
+// FunctionInfo holds optional information about a function or a method.
+type FunctionInfo struct {
+	// Literals prepare literal arguments when the expression is parsed,
+	// indexed by argument position, method receivers are at position 0.
+	// Nil entries leave arguments as is. For variadic functions the last
+	// entry applies to all remaining arguments.
+	Literals []LiteralFunc
+	// Pure marks functions whose result depends only on their arguments,
+	// so Simplify can call them when all arguments are literals.
+	Pure bool
+	// Deprecated is set for functions that should no longer be used,
+	// the text tells what to use instead, e.g. "use hasPrefix instead".
+	Deprecated string
+	// Cost is the cost of calling the function, see EstimateCost,
+	// calls cost 1 if it is nil.
+	Cost *Cost
 }
 
+// literal returns the function preparing the literal argument at index i.
+func (f FunctionInfo) literal(i int, variadic bool) LiteralFunc {
+	if i < len(f.Literals) {
+		return f.Literals[i]
+	}
+	if variadic && len(f.Literals) != 0 {
+		return f.Literals[len(f.Literals)-1]
+	}
+	return nil
+}
+
+// LiteralFunc validates a literal argument and returns the value passed to
+// the function instead, e.g. a compiled regular expression. Parse calls it
+// before evaluating the other arguments, so invalid literals are reported
+// as errors with their position. The work is repeated on every Parse call,
+// callers parsing the same expression often should keep the result.
+type LiteralFunc func(literal any) (any, error)
+
 // GetIdentifierFn function returns identifier based on selector
 // e.g. id.field.subfield will be passed as.
 // GetIdentifierFn([]string{"id", "field", "subfield"}).
@@ -99,6 +162,128 @@ type Operators struct {
 	NOT any
 }
 
+// Module is a set of functions and methods that can be merged into Def,
+// e.g. StringsModule.
+type Module struct {
+	// Functions are merged into Def.Functions.
+	Functions map[string]any
+	// Methods are merged into Def.Methods.
+	Methods map[string]any
+	// FunctionInfo is merged into Def.FunctionInfo.
+	FunctionInfo map[string]FunctionInfo
+}
+
+// Merge returns a copy of the definition with functions and methods of the
+// modules added. Merging fails if a name is already taken by another function,
+// or has different FunctionInfo.
+func (d Def) Merge(modules ...Module) (Def, error) {
+	functions := copyFunctions(d.Functions)
+	methods := copyFunctions(d.Methods)
+	info := make(map[string]FunctionInfo, len(d.FunctionInfo))
+	for name, fi := range d.FunctionInfo {
+		info[name] = fi
+	}
+	for _, m := range modules {
+		if err := mergeFunctions(functions, m.Functions); err != nil {
+			return Def{}, trace.Wrap(err)
+		}
+		if err := mergeFunctions(methods, m.Methods); err != nil {
+			return Def{}, trace.Wrap(err)
+		}
+		for name, fi := range m.FunctionInfo {
+			if existing, ok := info[name]; ok && !sameFunctionInfo(existing, fi) {
+				return Def{}, trace.AlreadyExists("function info of %q is already defined", name)
+			}
+			info[name] = fi
+		}
+	}
+	d.Functions = functions
+	d.Methods = methods
+	d.FunctionInfo = info
+	return d, nil
+}
+
+// pureFunctions returns FunctionInfo marking all functions as pure.
+func pureFunctions(fns map[string]any) map[string]FunctionInfo {
+	out := make(map[string]FunctionInfo, len(fns))
+	for name := range fns {
+		out[name] = FunctionInfo{Pure: true}
+	}
+	return out
+}
+
+// withCosts sets costs of the functions.
+func withCosts(info map[string]FunctionInfo, costs map[string]*Cost) map[string]FunctionInfo {
+	for name, cost := range costs {
+		fi := info[name]
+		fi.Cost = cost
+		info[name] = fi
+	}
+	return info
+}
+
+func copyFunctions(in map[string]any) map[string]any {
+	out := make(map[string]any, len(in))
+	for name, fn := range in {
+		out[name] = fn
+	}
+	return out
+}
+
+func mergeFunctions(dst, src map[string]any) error {
+	for name, fn := range src {
+		if existing, ok := dst[name]; ok && !sameFunction(existing, fn) {
+			return trace.AlreadyExists("function %q is already defined", name)
+		}
+		dst[name] = fn
+	}
+	return nil
+}
+
+// sameFunction returns true if both values are the same function,
+// so modules sharing a function, e.g. len, can be merged together.
+func sameFunction(a, b any) bool {
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	return av.Kind() == reflect.Func && bv.Kind() == reflect.Func &&
+		av.Type() == bv.Type() && av.Pointer() == bv.Pointer()
+}
+
+// sameFunctionInfo returns true if both infos are the same,
+// e.g. for functions shared by modules.
+func sameFunctionInfo(a, b FunctionInfo) bool {
+	if a.Pure != b.Pure || a.Deprecated != b.Deprecated || len(a.Literals) != len(b.Literals) ||
+		!sameCost(a.Cost, b.Cost) {
+		return false
+	}
+	for i := range a.Literals {
+		if !sameOptionalFunction(a.Literals[i], b.Literals[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameCost returns true if both costs are nil or describe the same cost.
+func sameCost(a, b *Cost) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Base == b.Base && a.PerUnit == b.PerUnit && a.Arg == b.Arg &&
+		sameOptionalFunction(a.Estimate, b.Estimate)
+}
+
+// sameOptionalFunction returns true if both functions are nil or the same.
+func sameOptionalFunction(a, b any) bool {
+	isNil := func(v reflect.Value) bool {
+		return !v.IsValid() || v.IsNil()
+	}
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	if isNil(av) || isNil(bv) {
+		return isNil(av) && isNil(bv)
+	}
+	return sameFunction(a, b)
+}
+
 // Parser takes the string with expression and calls the operators and functions.
 type Parser interface {
 	Parse(string) (any, error)
@@ -0,0 +1,77 @@
+package predicate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefMerge(t *testing.T) {
+	t.Parallel()
+
+	d := Def{
+		Functions: map[string]any{"equals": Equals},
+	}
+	merged, err := d.Merge(StringsModule(), StringsModule())
+	require.NoError(t, err)
+	require.Contains(t, merged.Functions, "equals")
+	require.Contains(t, merged.Functions, "lower")
+	require.Contains(t, merged.Methods, "lower")
+	require.NotContains(t, d.Functions, "lower", "original definition is not modified")
+
+	_, err = Def{
+		Functions: map[string]any{"lower": Equals},
+	}.Merge(StringsModule())
+	require.True(t, trace.IsAlreadyExists(err), "unexpected error %v", err)
+
+	_, err = Def{
+		FunctionInfo: map[string]FunctionInfo{"lower": {Deprecated: "use toLower instead"}},
+	}.Merge(StringsModule())
+	require.True(t, trace.IsAlreadyExists(err), "unexpected error %v", err)
+}
+
+// testIdentifiers returns GetIdentifierFn looking up the last segment
+// of selectors in values, e.g. request.ip looks up ip.
+func testIdentifiers(values map[string]any) GetIdentifierFn {
+	return func(selector []string) (any, error) {
+		if v, ok := values[selector[len(selector)-1]]; ok {
+			return v, nil
+		}
+		return nil, trace.NotFound("%v is not found", strings.Join(selector, "."))
+	}
+}
+
+// newTestParser returns a parser of the definition with the modules merged
+// in. Logical operators default to And, Or and Not, and identifiers to
+// values, see testIdentifiers.
+func newTestParser(t *testing.T, d Def, values map[string]any, modules ...Module) Parser {
+	if d.Operators.AND == nil {
+		d.Operators.AND = And
+	}
+	if d.Operators.OR == nil {
+		d.Operators.OR = Or
+	}
+	if d.Operators.NOT == nil {
+		d.Operators.NOT = Not
+	}
+	if d.GetIdentifier == nil {
+		d.GetIdentifier = testIdentifiers(values)
+	}
+	d, err := d.Merge(modules...)
+	require.NoError(t, err)
+	p, err := NewParser(d)
+	require.NoError(t, err)
+	return p
+}
+
+// parseAndEval parses the input and evaluates boolean predicates.
+func parseAndEval(t *testing.T, p Parser, input string) any {
+	out, err := p.Parse(input)
+	require.NoError(t, err, input)
+	if pred, ok := out.(BoolPredicate); ok {
+		return pred()
+	}
+	return out
+}
@@ -0,0 +1,211 @@
+package predicate
+
+import (
+	containerlist "container/list"
+	"regexp"
+	"regexp/syntax"
+	"sync"
+
+	"github.com/gravitational/trace"
+)
+
+const (
+	// DefaultMaxPatternLength is the default limit of regular expression length.
+	DefaultMaxPatternLength = 1024
+	// DefaultMaxProgramSize is the default limit of the number of instructions
+	// in a compiled regular expression.
+	DefaultMaxProgramSize = 10000
+	// DefaultRegexCacheSize is the default number of compiled regular
+	// expressions kept for patterns that are not literals.
+	DefaultRegexCacheSize = 256
+)
+
+// RegexOptions configures RegexModule.
+type RegexOptions struct {
+	// MaxPatternLength limits the length of patterns in bytes.
+	MaxPatternLength int
+	// MaxProgramSize limits the number of instructions in compiled patterns.
+	MaxProgramSize int
+	// CacheSize is the number of compiled patterns kept in the cache.
+	CacheSize int
+}
+
+// CheckAndSetDefaults checks and sets default values.
+func (o *RegexOptions) CheckAndSetDefaults() error {
+	if o.MaxPatternLength < 0 || o.MaxProgramSize < 0 || o.CacheSize < 0 {
+		return trace.BadParameter("regex limits can not be negative")
+	}
+	if o.MaxPatternLength == 0 {
+		o.MaxPatternLength = DefaultMaxPatternLength
+	}
+	if o.MaxProgramSize == 0 {
+		o.MaxProgramSize = DefaultMaxProgramSize
+	}
+	if o.CacheSize == 0 {
+		o.CacheSize = DefaultRegexCacheSize
+	}
+	return nil
+}
+
+// RegexModule returns regular expression functions using RE2 syntax,
+// available both as functions and as methods:
+//
+//	matches(value, pattern)                   - returns BoolPredicate
+//	regexReplace(value, pattern, replacement) - replaces all matches,
+//	                                            replacement may refer to
+//	                                            groups, e.g. $1
+//
+// Patterns that are not literals are compiled when called and kept in
+// a bounded LRU cache.
+func RegexModule(opts RegexOptions) (Module, error) {
+	if err := opts.CheckAndSetDefaults(); err != nil {
+		return Module{}, trace.Wrap(err)
+	}
+	r := &regexCompiler{
+		opts:  opts,
+		cache: newRegexCache(opts.CacheSize),
+	}
+	fns := map[string]any{
+		"matches":      r.matches,
+		"regexReplace": r.replace,
+	}
+	return Module{
+		Functions: fns,
+		Methods:   copyFunctions(fns),
+		FunctionInfo: map[string]FunctionInfo{
+			"matches":      {Literals: []LiteralFunc{nil, r.literal}, Pure: true, Cost: regexMatchCost},
+			"regexReplace": {Literals: []LiteralFunc{nil, r.literal, nil}, Pure: true, Cost: regexReplaceCost},
+		},
+	}, nil
+}
+
+var (
+	// regexMatchCost is linear in the input and in the pattern,
+	// which is compiled unless it is a literal.
+	regexMatchCost = linearSum(1, 1, 0, 1)
+	// regexReplaceCost is also linear in the replacement.
+	regexReplaceCost = linearSum(1, 1, 0, 1, 2)
+)
+
+type regexCompiler struct {
+	opts  RegexOptions
+	cache *regexCache
+}
+
+func (r *regexCompiler) literal(pattern any) (any, error) {
+	return r.regexp(pattern)
+}
+
+// regexp returns the compiled pattern, pattern is either a string
+// or a regular expression compiled when the expression was parsed.
+func (r *regexCompiler) regexp(pattern any) (*regexp.Regexp, error) {
+	switch p := pattern.(type) {
+	case *regexp.Regexp:
+		return p, nil
+	case string:
+		if re, ok := r.cache.get(p); ok {
+			return re, nil
+		}
+		re, err := r.compile(p)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		r.cache.add(p, re)
+		return re, nil
+	default:
+		return nil, trace.BadParameter("expected pattern to be a string, got %T", pattern)
+	}
+}
+
+func (r *regexCompiler) compile(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > r.opts.MaxPatternLength {
+		return nil, trace.LimitExceeded("regular expression is longer than %v bytes", r.opts.MaxPatternLength)
+	}
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, trace.BadParameter("invalid regular expression %q: %v", pattern, err)
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return nil, trace.BadParameter("invalid regular expression %q: %v", pattern, err)
+	}
+	if len(prog.Inst) > r.opts.MaxProgramSize {
+		return nil, trace.LimitExceeded("regular expression %q is too complex", pattern)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, trace.BadParameter("invalid regular expression %q: %v", pattern, err)
+	}
+	return re, nil
+}
+
+func (r *regexCompiler) matches(value string, pattern any) (BoolPredicate, error) {
+	re, err := r.regexp(pattern)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return func() bool {
+		return re.MatchString(value)
+	}, nil
+}
+
+func (r *regexCompiler) replace(value string, pattern any, replacement string) (string, error) {
+	re, err := r.regexp(pattern)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return re.ReplaceAllString(value, replacement), nil
+}
+
+// regexCache is a LRU cache of compiled regular expressions.
+type regexCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*containerlist.Element
+	order   *containerlist.List
+}
+
+type regexCacheEntry struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+func newRegexCache(size int) *regexCache {
+	return &regexCache{
+		size:    size,
+		entries: make(map[string]*containerlist.Element),
+		order:   containerlist.New(),
+	}
+}
+
+func (c *regexCache) get(pattern string) (*regexp.Regexp, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[pattern]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*regexCacheEntry).re, true
+}
+
+func (c *regexCache) add(pattern string, re *regexp.Regexp) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[pattern]; ok {
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[pattern] = c.order.PushFront(&regexCacheEntry{pattern: pattern, re: re})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*regexCacheEntry).pattern)
+	}
+}
+
+func (c *regexCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,119 @@
+package predicate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegexModule(t *testing.T) {
+	t.Parallel()
+
+	regex, err := RegexModule(RegexOptions{MaxPatternLength: 64, MaxProgramSize: 100, CacheSize: 2})
+	require.NoError(t, err)
+
+	p := newTestParser(t, Def{}, map[string]any{
+		"name":       "prod-db-1",
+		"pattern":    "^prod-",
+		"badPattern": "(",
+	}, regex)
+
+	for _, tc := range []struct {
+		desc   string
+		input  string
+		expect any
+	}{
+		{desc: "literal pattern", input: `matches(name, "^prod-.*$")`, expect: true},
+		{desc: "no match", input: `matches(name, "^dev-")`, expect: false},
+		{desc: "method", input: `name.matches("db-[0-9]+$")`, expect: true},
+		{desc: "dynamic pattern", input: `matches(name, pattern)`, expect: true},
+		{desc: "parenthesized literal", input: `matches(name, ("-1$"))`, expect: true},
+		{desc: "with operators", input: `matches(name, "^prod") && !matches(name, "^dev")`, expect: true},
+		{desc: "replace", input: `regexReplace(name, "^(\\w+)-db", "$1-cache")`, expect: "prod-cache-1"},
+		{desc: "replace method", input: `name.regexReplace("[0-9]", "N")`, expect: "prod-db-N"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			require.Equal(t, tc.expect, parseAndEval(t, p, tc.input))
+		})
+	}
+
+	for _, tc := range []struct {
+		desc          string
+		input         string
+		expectError   func(error) bool
+		expectMessage string
+	}{
+		{
+			desc:          "invalid literal",
+			input:         `matches(name, "[a-")`,
+			expectError:   trace.IsBadParameter,
+			expectMessage: "1:15: invalid regular expression",
+		},
+		{
+			desc:          "invalid literal on second line",
+			input:         "matches(name, \"^a\") &&\n  name.matches(\"(\")",
+			expectError:   trace.IsBadParameter,
+			expectMessage: "2:16: invalid regular expression",
+		},
+		{
+			desc:          "invalid literal before missing identifier",
+			input:         `matches(missing, "[a-")`,
+			expectError:   trace.IsBadParameter,
+			expectMessage: "1:18: invalid regular expression",
+		},
+		{
+			desc:        "invalid dynamic pattern",
+			input:       `matches(name, badPattern)`,
+			expectError: trace.IsBadParameter,
+		},
+		{
+			desc:          "pattern too long",
+			input:         `matches(name, "` + strings.Repeat("a", 65) + `")`,
+			expectError:   trace.IsLimitExceeded,
+			expectMessage: "1:15:",
+		},
+		{
+			desc:        "program too large",
+			input:       `matches(name, "a{1000}")`,
+			expectError: trace.IsLimitExceeded,
+		},
+		{
+			desc:        "non string pattern",
+			input:       `matches(name, 1)`,
+			expectError: trace.IsBadParameter,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := p.Parse(tc.input)
+			require.True(t, tc.expectError(err), "unexpected error %v", err)
+			require.Contains(t, err.Error(), tc.expectMessage)
+		})
+	}
+}
+
+func TestRegexCache(t *testing.T) {
+	t.Parallel()
+
+	r := &regexCompiler{cache: newRegexCache(2)}
+	require.NoError(t, r.opts.CheckAndSetDefaults())
+
+	a, err := r.regexp("a")
+	require.NoError(t, err)
+	_, err = r.regexp("b")
+	require.NoError(t, err)
+
+	// Using a moves it to the front, so c evicts b.
+	again, err := r.regexp("a")
+	require.NoError(t, err)
+	require.Same(t, a, again)
+	_, err = r.regexp("c")
+	require.NoError(t, err)
+
+	require.Equal(t, 2, r.cache.len())
+	_, ok := r.cache.get("b")
+	require.False(t, ok)
+	_, ok = r.cache.get("a")
+	require.True(t, ok)
+}
@@ -0,0 +1,617 @@
+package predicate
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"github.com/gravitational/trace"
+	"github.com/vulcand/predicate/internal/astutil"
+)
+
+// Satisfiability is the result of a satisfiability check.
+type Satisfiability int
+
+const (
+	// SatUnknown means the solver could not decide, e.g. because the rule
+	// calls functions the solver knows nothing about.
+	SatUnknown Satisfiability = iota
+	// Satisfiable means some values of identifiers make the rule true.
+	Satisfiable
+	// Unsatisfiable means the rule is false for any values of identifiers.
+	Unsatisfiable
+)
+
+// String returns satisfiable, unsatisfiable or unknown.
+func (s Satisfiability) String() string {
+	switch s {
+	case Satisfiable:
+		return "satisfiable"
+	case Unsatisfiable:
+		return "unsatisfiable"
+	default:
+		return "unknown"
+	}
+}
+
+// SatOptions configures the satisfiability check.
+type SatOptions struct {
+	// NormalFormOptions limits the size of the DNF the solver works on,
+	// bigger rules are reported as SatUnknown.
+	NormalFormOptions
+	// EqualsFunctions are names of functions that test equality of two
+	// values, like Equals. Defaults to equals.
+	EqualsFunctions []string
+	// ContainsFunctions are names of functions that test if the list
+	// in the first argument contains the second argument, like Contains.
+	// Defaults to contains.
+	ContainsFunctions []string
+}
+
+// CheckAndSetDefaults checks and sets default values.
+func (o *SatOptions) CheckAndSetDefaults() error {
+	if err := o.NormalFormOptions.CheckAndSetDefaults(); err != nil {
+		return trace.Wrap(err)
+	}
+	if o.EqualsFunctions == nil {
+		o.EqualsFunctions = []string{"equals"}
+	}
+	if o.ContainsFunctions == nil {
+		o.ContainsFunctions = []string{"contains"}
+	}
+	return nil
+}
+
+// SatResult is the result of Satisfiable.
+type SatResult struct {
+	// Status tells if the rule is satisfiable.
+	Status Satisfiability
+	// Model holds values of identifiers that satisfy the rule if it is
+	// satisfiable. Identifiers missing from the model do not matter, the
+	// rule is true for any of their values. Identifiers used as booleans
+	// are bool values.
+	Model Model
+}
+
+// Model assigns values to identifiers, keyed by paths like user.name.
+type Model map[string]any
+
+// GetIdentifier is GetIdentifierFn returning values of the model,
+// identifiers not in the model fail with trace.NotFound.
+func (m Model) GetIdentifier(selector []string) (any, error) {
+	path := strings.Join(selector, ".")
+	val, ok := m[path]
+	if !ok {
+		return nil, trace.NotFound("%v is not in the model", path)
+	}
+	return val, nil
+}
+
+// Satisfiable checks if the rule can be true for some values of identifiers,
+// e.g. env == "prod" && env == "dev" can not. The solver understands atoms
+// comparing identifiers with literals using the comparison operators,
+// equality and membership functions named in opts, and identifiers used as
+// booleans. Operators are expected to have their usual meaning as defined by
+// Compare. Identifiers compared with integers only may be integers, so
+// x > 1 && x < 2 is SatUnknown, but x > 1.0 && x < 2.0 is satisfiable.
+// Other atoms are opaque, rules that depend on them are reported
+// as SatUnknown unless an atom and its negation are both required.
+func (d Def) Satisfiable(in string, opts SatOptions) (SatResult, error) {
+	expr, err := parser.ParseExpr(in)
+	if err != nil {
+		return SatResult{}, err
+	}
+	return d.SatisfiableExpr(expr, opts)
+}
+
+// SatisfiableExpr is Satisfiable for parsed expressions.
+func (d Def) SatisfiableExpr(expr ast.Expr, opts SatOptions) (SatResult, error) {
+	if err := opts.CheckAndSetDefaults(); err != nil {
+		return SatResult{}, trace.Wrap(err)
+	}
+	// The solver negates atoms it understands itself, inverses would
+	// replace them with atoms it does not, e.g. notContains.
+	d.Inverses = nil
+	dnf, err := d.DNF(expr, opts.NormalFormOptions)
+	if err != nil {
+		if trace.IsLimitExceeded(err) {
+			return SatResult{Status: SatUnknown}, nil
+		}
+		return SatResult{}, trace.Wrap(err)
+	}
+	status := Unsatisfiable
+	for _, clause := range dnf.Clauses {
+		clauseStatus, model := solveClause(clause, opts)
+		switch clauseStatus {
+		case Satisfiable:
+			return SatResult{Status: Satisfiable, Model: model}, nil
+		case SatUnknown:
+			status = SatUnknown
+		}
+	}
+	return SatResult{Status: status}, nil
+}
+
+// solveClause decides if the DNF clause is satisfiable.
+func solveClause(clause []ast.Expr, opts SatOptions) (Satisfiability, Model) {
+	s := newClauseSolver(opts)
+	for _, lit := range clause {
+		s.add(lit)
+	}
+	return s.solve()
+}
+
+// varKind is the way an identifier is used in a clause.
+type varKind int
+
+const (
+	varBool varKind = iota + 1
+	varScalar
+	varList
+)
+
+// comparison is a constraint on a scalar identifier, e.g. < 10.
+type comparison struct {
+	op    token.Token
+	value any
+}
+
+// holds returns true if the value satisfies the constraint.
+func (c comparison) holds(v any) (bool, error) {
+	if c.op == token.EQL || c.op == token.NEQ {
+		return sameValue(v, c.value) == (c.op == token.EQL), nil
+	}
+	cmp, err := Compare(v, c.value)
+	if err != nil {
+		return false, trace.Wrap(err)
+	}
+	switch c.op {
+	case token.LSS:
+		return cmp < 0, nil
+	case token.LEQ:
+		return cmp <= 0, nil
+	case token.GTR:
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// variable collects constraints on an identifier in a clause.
+type variable struct {
+	kind        varKind
+	boolValues  []bool
+	comparisons []comparison
+	contains    []any
+	excludes    []any
+	// mixed is set when the identifier is used in different ways,
+	// e.g. as a boolean and in a comparison.
+	mixed bool
+}
+
+// clauseSolver decides if a conjunction of literals is satisfiable.
+type clauseSolver struct {
+	opts  SatOptions
+	vars  map[string]*variable
+	order []string
+	// opaque holds polarities of atoms the solver does not understand.
+	opaque map[string][]bool
+}
+
+func newClauseSolver(opts SatOptions) *clauseSolver {
+	return &clauseSolver{
+		opts:   opts,
+		vars:   make(map[string]*variable),
+		opaque: make(map[string][]bool),
+	}
+}
+
+// variable returns the variable of the identifier path used as kind.
+func (s *clauseSolver) variable(path string, kind varKind) *variable {
+	v, ok := s.vars[path]
+	if !ok {
+		v = &variable{kind: kind}
+		s.vars[path] = v
+		s.order = append(s.order, path)
+	}
+	if v.kind != kind {
+		v.mixed = true
+	}
+	return v
+}
+
+// add adds the literal, an atom or a negated atom, to the clause.
+func (s *clauseSolver) add(lit ast.Expr) {
+	atom, negated := astutil.Unparen(lit), false
+	if u, ok := atom.(*ast.UnaryExpr); ok && u.Op == token.NOT {
+		atom, negated = astutil.Unparen(u.X), true
+	}
+	switch a := atom.(type) {
+	case *ast.Ident, *ast.SelectorExpr:
+		if path, ok := identifierPath(a); ok {
+			v := s.variable(path, varBool)
+			v.boolValues = append(v.boolValues, !negated)
+			return
+		}
+	case *ast.BinaryExpr:
+		if path, c, ok := comparisonAtom(a.X, a.Op, a.Y); ok {
+			s.addComparison(path, c, negated)
+			return
+		}
+	case *ast.CallExpr:
+		if s.addCall(a, negated) {
+			return
+		}
+	}
+	key := astutil.ExprString(atom)
+	s.opaque[key] = append(s.opaque[key], !negated)
+}
+
+func (s *clauseSolver) addComparison(path string, c comparison, negated bool) {
+	if negated {
+		c.op = negateComparison(c.op)
+	}
+	v := s.variable(path, varScalar)
+	v.comparisons = append(v.comparisons, c)
+}
+
+// addCall adds equality and membership functions, it returns false
+// for other functions.
+func (s *clauseSolver) addCall(call *ast.CallExpr, negated bool) bool {
+	id, ok := call.Fun.(*ast.Ident)
+	if !ok || len(call.Args) != 2 {
+		return false
+	}
+	switch {
+	case containsString(s.opts.EqualsFunctions, id.Name):
+		path, c, ok := comparisonAtom(call.Args[0], token.EQL, call.Args[1])
+		if !ok {
+			return false
+		}
+		s.addComparison(path, c, negated)
+		return true
+	case containsString(s.opts.ContainsFunctions, id.Name):
+		path, ok := identifierPath(call.Args[0])
+		if !ok {
+			return false
+		}
+		val, ok := literalValue(call.Args[1])
+		if !ok {
+			return false
+		}
+		v := s.variable(path, varList)
+		if negated {
+			v.excludes = append(v.excludes, val)
+		} else {
+			v.contains = append(v.contains, val)
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// solve returns the status of the clause and the model if it is satisfiable.
+func (s *clauseSolver) solve() (Satisfiability, Model) {
+	for _, polarities := range s.opaque {
+		for _, p := range polarities[1:] {
+			if p != polarities[0] {
+				return Unsatisfiable, nil
+			}
+		}
+	}
+	status := Satisfiable
+	if len(s.opaque) != 0 {
+		status = SatUnknown
+	}
+	model := make(Model, len(s.vars))
+	for _, path := range s.order {
+		v := s.vars[path]
+		if v.mixed {
+			status = SatUnknown
+			continue
+		}
+		var (
+			varStatus Satisfiability
+			val       any
+		)
+		switch v.kind {
+		case varBool:
+			varStatus, val = solveBool(v.boolValues)
+		case varScalar:
+			varStatus, val = solveScalar(v.comparisons)
+		case varList:
+			varStatus, val = solveList(v.contains, v.excludes)
+		}
+		switch varStatus {
+		case Unsatisfiable:
+			return Unsatisfiable, nil
+		case SatUnknown:
+			status = SatUnknown
+		}
+		model[path] = val
+	}
+	if status != Satisfiable {
+		return status, nil
+	}
+	return Satisfiable, model
+}
+
+func solveBool(values []bool) (Satisfiability, any) {
+	for _, b := range values[1:] {
+		if b != values[0] {
+			return Unsatisfiable, nil
+		}
+	}
+	return Satisfiable, values[0]
+}
+
+func solveList(contains, excludes []any) (Satisfiability, any) {
+	for _, c := range contains {
+		for _, e := range excludes {
+			if sameValue(c, e) {
+				return Unsatisfiable, nil
+			}
+		}
+	}
+	strs := make([]string, 0, len(contains))
+	for _, c := range contains {
+		s, ok := c.(string)
+		if !ok {
+			return Satisfiable, uniqueValues(contains)
+		}
+		strs = append(strs, s)
+	}
+	return Satisfiable, uniqueStrings(strs)
+}
+
+// solveScalar decides if the comparisons can hold for one value and returns
+// the value. Numbers are treated as reals, so only contradicting equalities
+// and empty intervals are unsatisfiable. Identifiers compared with integers
+// only may be integers, so models for them are integers, and x > 1 && x < 2
+// is SatUnknown.
+func solveScalar(comparisons []comparison) (Satisfiability, any) {
+	var eq *comparison
+	for i, c := range comparisons {
+		if c.op != token.EQL {
+			continue
+		}
+		if eq == nil {
+			eq = &comparisons[i]
+			continue
+		}
+		if !sameValue(eq.value, c.value) {
+			return Unsatisfiable, nil
+		}
+	}
+	if eq != nil {
+		return checkCandidate(comparisons, eq.value, Unsatisfiable)
+	}
+
+	lower, upper, err := bounds(comparisons)
+	if err != nil {
+		return SatUnknown, nil
+	}
+	if lower != nil && upper != nil {
+		cmp, err := Compare(lower.value, upper.value)
+		if err != nil {
+			return SatUnknown, nil
+		}
+		switch {
+		case cmp > 0:
+			return Unsatisfiable, nil
+		case cmp == 0 && (lower.op == token.GTR || upper.op == token.LSS):
+			return Unsatisfiable, nil
+		case cmp == 0:
+			// The interval is a single value.
+			return checkCandidate(comparisons, lower.value, Unsatisfiable)
+		}
+	}
+	integers := allInts(comparisons)
+	for _, candidate := range candidates(comparisons) {
+		if _, ok := candidate.(int); integers && !ok {
+			continue
+		}
+		if status, val := checkCandidate(comparisons, candidate, SatUnknown); status == Satisfiable {
+			return status, val
+		}
+	}
+	return SatUnknown, nil
+}
+
+// allInts returns true if all comparisons compare with integers.
+func allInts(comparisons []comparison) bool {
+	for _, c := range comparisons {
+		if _, ok := c.value.(int); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// checkCandidate returns Satisfiable and the value if it satisfies all
+// comparisons, and failed otherwise.
+func checkCandidate(comparisons []comparison, v any, failed Satisfiability) (Satisfiability, any) {
+	for _, c := range comparisons {
+		ok, err := c.holds(v)
+		if err != nil {
+			return SatUnknown, nil
+		}
+		if !ok {
+			return failed, nil
+		}
+	}
+	return Satisfiable, v
+}
+
+// bounds returns the tightest lower and upper bounds.
+func bounds(comparisons []comparison) (*comparison, *comparison, error) {
+	var lower, upper *comparison
+	for i, c := range comparisons {
+		switch c.op {
+		case token.GTR, token.GEQ:
+			tighter, err := tighterBound(lower, &comparisons[i], 1)
+			if err != nil {
+				return nil, nil, trace.Wrap(err)
+			}
+			lower = tighter
+		case token.LSS, token.LEQ:
+			tighter, err := tighterBound(upper, &comparisons[i], -1)
+			if err != nil {
+				return nil, nil, trace.Wrap(err)
+			}
+			upper = tighter
+		}
+	}
+	return lower, upper, nil
+}
+
+// tighterBound returns the tighter of two lower bounds if dir is 1,
+// and of two upper bounds if dir is -1.
+func tighterBound(current, c *comparison, dir int) (*comparison, error) {
+	if current == nil {
+		return c, nil
+	}
+	cmp, err := Compare(c.value, current.value)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	switch {
+	case cmp*dir > 0:
+		return c, nil
+	case cmp == 0 && (c.op == token.GTR || c.op == token.LSS):
+		// Strict bounds are tighter than inclusive ones.
+		return c, nil
+	default:
+		return current, nil
+	}
+}
+
+// candidates returns values near the values of comparisons
+// to try as the value of a scalar identifier.
+func candidates(comparisons []comparison) []any {
+	var out []any
+	for _, c := range comparisons {
+		switch v := c.value.(type) {
+		case string:
+			out = append(out, v, v+"a", v+"~")
+		case bool:
+			out = append(out, !v, v)
+		case int:
+			out = append(out, v, v+1, v-1)
+		default:
+			if f, ok := toFloat(v); ok {
+				out = append(out, f, f+1, f-1)
+			}
+		}
+	}
+	lower, upper, err := bounds(comparisons)
+	if err == nil && lower != nil && upper != nil {
+		lf, lok := toFloat(lower.value)
+		uf, uok := toFloat(upper.value)
+		if lok && uok {
+			out = append(out, (lf+uf)/2)
+		}
+	}
+	return append(out, "", 0)
+}
+
+// comparisonAtom returns the identifier path and the constraint of
+// comparisons of identifiers with literals, e.g. x < 1 or 1 > x.
+func comparisonAtom(x ast.Expr, op token.Token, y ast.Expr) (string, comparison, bool) {
+	if _, ok := comparisonTokens[op.String()]; !ok {
+		return "", comparison{}, false
+	}
+	if path, ok := identifierPath(x); ok {
+		if val, ok := literalValue(y); ok {
+			return path, comparison{op: op, value: val}, true
+		}
+	}
+	if path, ok := identifierPath(y); ok {
+		if val, ok := literalValue(x); ok {
+			return path, comparison{op: swapComparison(op), value: val}, true
+		}
+	}
+	return "", comparison{}, false
+}
+
+// identifierPath returns the path of identifiers and selectors,
+// true and false are constants and not identifiers.
+func identifierPath(expr ast.Expr) (string, bool) {
+	if _, ok := boolConst(expr); ok {
+		return "", false
+	}
+	selector, ok := astutil.SelectorOf(expr)
+	if !ok {
+		return "", false
+	}
+	return strings.Join(selector, "."), true
+}
+
+// negateComparison returns the operator that is true when op is false.
+func negateComparison(op token.Token) token.Token {
+	switch op {
+	case token.EQL:
+		return token.NEQ
+	case token.NEQ:
+		return token.EQL
+	case token.LSS:
+		return token.GEQ
+	case token.GEQ:
+		return token.LSS
+	case token.GTR:
+		return token.LEQ
+	default:
+		return token.GTR
+	}
+}
+
+// swapComparison returns the operator with swapped operands, e.g. > for <.
+func swapComparison(op token.Token) token.Token {
+	switch op {
+	case token.LSS:
+		return token.GTR
+	case token.GTR:
+		return token.LSS
+	case token.LEQ:
+		return token.GEQ
+	case token.GEQ:
+		return token.LEQ
+	default:
+		return op
+	}
+}
+
+// sameValue returns true if the values are equal, numbers of different
+// types are compared by value.
+func sameValue(a, b any) bool {
+	if cmp, err := Compare(a, b); err == nil {
+		return cmp == 0
+	}
+	return a == b
+}
+
+func uniqueValues(values []any) []any {
+	out := make([]any, 0, len(values))
+	for _, v := range values {
+		found := false
+		for _, o := range out {
+			found = found || sameValue(o, v)
+		}
+		if !found {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func uniqueStrings(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if !containsString(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,136 @@
+package predicate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSatisfiable(t *testing.T) {
+	t.Parallel()
+
+	d := Def{
+		Operators: Operators{
+			AND: And,
+			OR:  Or,
+			NOT: Not,
+			EQ:  EqualValues,
+			NEQ: NotEqualValues,
+			LT:  Less,
+			LE:  LessOrEqual,
+			GT:  Greater,
+			GE:  GreaterOrEqual,
+		},
+		Functions: map[string]any{
+			"equals":   Equals,
+			"contains": Contains,
+			"lower":    strings.ToLower,
+		},
+	}
+	tests := []struct {
+		in   string
+		want Satisfiability
+	}{
+		{in: `env == "prod" && env == "dev"`, want: Unsatisfiable},
+		{in: `env == "prod" && env != "prod"`, want: Unsatisfiable},
+		{in: `env == "prod" && !(env == "prod")`, want: Unsatisfiable},
+		{in: `equals(env, "prod") && env == "dev"`, want: Unsatisfiable},
+		{in: `age > 10 && age < 5`, want: Unsatisfiable},
+		{in: `age > 10 && 10 > age`, want: Unsatisfiable},
+		{in: `age > 10 && age <= 10`, want: Unsatisfiable},
+		{in: `age >= 10 && age <= 10 && age != 10`, want: Unsatisfiable},
+		{in: `age > 10 && !(age > 5)`, want: Unsatisfiable},
+		{in: `contains(roles, "a") && !contains(roles, "a")`, want: Unsatisfiable},
+		{in: `admin && !admin`, want: Unsatisfiable},
+		{in: `lower(name) == "a" && !(lower(name) == "a")`, want: Unsatisfiable},
+		{in: `(env == "prod" || env == "dev") && env == "stage"`, want: Unsatisfiable},
+		{in: `env == "prod" && env == "dev" || admin`, want: Satisfiable},
+		{in: `env == "prod"`, want: Satisfiable},
+		{in: `env != "prod"`, want: Satisfiable},
+		{in: `age > 10 && age < 12`, want: Satisfiable},
+		{in: `age > 10.0 && age < 11.0`, want: Satisfiable},
+		{in: `age > 10 && age < 10.5`, want: Satisfiable},
+		{in: `age >= 10 && age <= 10`, want: Satisfiable},
+		{in: `age > 1 && age != 2 && age != 3`, want: Satisfiable},
+		{in: `name > "a" && name < "b"`, want: Satisfiable},
+		{in: `contains(roles, "a") && contains(roles, "b") && !contains(roles, "c")`, want: Satisfiable},
+		{in: `user.admin && !user.banned`, want: Satisfiable},
+		{in: `lower(name) == "a"`, want: SatUnknown},
+		{in: `env == "prod" && contains(roles, user.name)`, want: SatUnknown},
+		{in: `admin && admin == "yes"`, want: SatUnknown},
+		{in: `name > "a" && age < 1 && name == 1`, want: SatUnknown},
+		// Integer identifiers have no values between 10 and 11,
+		// float ones do.
+		{in: `age > 10 && age < 11`, want: SatUnknown},
+		{in: `age >= 11 && age < 12 && age != 11`, want: SatUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			result, err := d.Satisfiable(tt.in, SatOptions{})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, result.Status, "got %v", result.Status)
+			if result.Status != Satisfiable {
+				require.Nil(t, result.Model)
+				return
+			}
+			// The model makes the rule true whatever the values
+			// of identifiers not in the model are.
+			d := d
+			d.ThreeValued = true
+			d.GetIdentifier = func(selector []string) (any, error) {
+				val, err := result.Model.GetIdentifier(selector)
+				if b, ok := val.(bool); ok {
+					return BoolPredicate(func() bool { return b }), nil
+				}
+				return val, err
+			}
+			p, err := NewParser(d)
+			require.NoError(t, err)
+			pr, err := p.Parse(tt.in)
+			require.NoError(t, err)
+			require.Equal(t, TriTrue, pr.(TriPredicate)(), "model %v", result.Model)
+		})
+	}
+}
+
+func TestSatisfiableModel(t *testing.T) {
+	t.Parallel()
+
+	result, err := Def{}.Satisfiable(`env == "prod" && contains(roles, "dev") && age > 10 && admin`, SatOptions{})
+	require.NoError(t, err)
+	require.Equal(t, Satisfiable, result.Status)
+	require.Equal(t, Model{
+		"env":   "prod",
+		"roles": []string{"dev"},
+		"age":   11,
+		"admin": true,
+	}, result.Model)
+
+	// Identifiers compared with integers get integer values.
+	result, err = Def{}.Satisfiable(`age > 1 && age < 3`, SatOptions{})
+	require.NoError(t, err)
+	require.Equal(t, Satisfiable, result.Status)
+	require.Equal(t, Model{"age": 2}, result.Model)
+
+	// Rules too big to check are unknown.
+	result, err = Def{}.Satisfiable(`(a || b) && (c || d) && (e || f)`, SatOptions{
+		NormalFormOptions: NormalFormOptions{MaxClauses: 4},
+	})
+	require.NoError(t, err)
+	require.Equal(t, SatUnknown, result.Status)
+}
+
+func TestSatisfiableInverses(t *testing.T) {
+	t.Parallel()
+
+	d := Def{Inverses: map[string]string{"contains": "notContains", "<": ">="}}
+	for _, in := range []string{
+		`contains(roles, "a") && !contains(roles, "a")`,
+		`age < 10 && !(age < 20)`,
+	} {
+		result, err := d.Satisfiable(in, SatOptions{})
+		require.NoError(t, err)
+		require.Equal(t, Unsatisfiable, result.Status, in)
+	}
+}
@@ -0,0 +1,465 @@
+package predicate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gravitational/trace"
+)
+
+// SemVer is a semantic version as defined by https://semver.org.
+// SemVer implements Comparable, so it works with the comparison operators
+// in this package, e.g. Less and GreaterOrEqual.
+type SemVer struct {
+	Major uint64
+	Minor uint64
+	Patch uint64
+	// Prerelease holds dot separated prerelease identifiers,
+	// e.g. ["rc", "1"] for 1.2.3-rc.1.
+	Prerelease []string
+	// Build is the build metadata, ignored by comparisons.
+	Build string
+}
+
+// ParseSemVer parses a version like 1.2.3, 1.2.3-rc.1+build or v1.2.3.
+func ParseSemVer(s string) (SemVer, error) {
+	v, parts, err := parseSemVerParts(s)
+	if err != nil {
+		return SemVer{}, trace.Wrap(err)
+	}
+	if parts != 3 {
+		return SemVer{}, trace.BadParameter("invalid semantic version %q: expected major.minor.patch", s)
+	}
+	return v, nil
+}
+
+// parseSemVerParts parses full or partial versions like 1.2, 1.x or *,
+// and returns the number of numeric parts that were present.
+func parseSemVerParts(s string) (SemVer, int, error) {
+	in := strings.TrimPrefix(s, "v")
+	var v SemVer
+	if i := strings.IndexByte(in, '+'); i >= 0 {
+		v.Build = in[i+1:]
+		if !validIdentifiers(v.Build, false) {
+			return SemVer{}, 0, trace.BadParameter("invalid semantic version %q: invalid build metadata", s)
+		}
+		in = in[:i]
+	}
+	if i := strings.IndexByte(in, '-'); i >= 0 {
+		pre := in[i+1:]
+		if !validIdentifiers(pre, true) {
+			return SemVer{}, 0, trace.BadParameter("invalid semantic version %q: invalid prerelease", s)
+		}
+		v.Prerelease = strings.Split(pre, ".")
+		in = in[:i]
+	}
+	fields := strings.Split(in, ".")
+	if len(fields) > 3 {
+		return SemVer{}, 0, trace.BadParameter("invalid semantic version %q", s)
+	}
+	numbers := []*uint64{&v.Major, &v.Minor, &v.Patch}
+	parts := 0
+	for i, field := range fields {
+		if field == "x" || field == "X" || field == "*" {
+			break
+		}
+		if !isNumeric(field) || (len(field) > 1 && field[0] == '0') {
+			return SemVer{}, 0, trace.BadParameter("invalid semantic version %q", s)
+		}
+		n, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return SemVer{}, 0, trace.BadParameter("invalid semantic version %q", s)
+		}
+		*numbers[i] = n
+		parts++
+	}
+	if parts < len(fields) {
+		// Everything after a wildcard has to be a wildcard too.
+		for _, field := range fields[parts:] {
+			if field != "x" && field != "X" && field != "*" {
+				return SemVer{}, 0, trace.BadParameter("invalid semantic version %q", s)
+			}
+		}
+	}
+	if parts < 3 && len(v.Prerelease) != 0 {
+		return SemVer{}, 0, trace.BadParameter("invalid semantic version %q: prerelease of a partial version", s)
+	}
+	return v, parts, nil
+}
+
+func validIdentifiers(s string, prerelease bool) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		for _, r := range id {
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-') {
+				return false
+			}
+		}
+		if prerelease && isNumeric(id) && len(id) > 1 && id[0] == '0' {
+			return false
+		}
+	}
+	return true
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the version in major.minor.patch[-prerelease][+build] format.
+func (v SemVer) String() string {
+	out := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) != 0 {
+		out += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if v.Build != "" {
+		out += "+" + v.Build
+	}
+	return out
+}
+
+// IsPrerelease returns true for prerelease versions like 1.2.3-rc.1.
+func (v SemVer) IsPrerelease() bool {
+	return len(v.Prerelease) != 0
+}
+
+// Compare compares the version with another SemVer or a version string
+// by semantic version precedence, build metadata is ignored.
+func (v SemVer) Compare(other any) (int, error) {
+	o, err := toSemVer(other)
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+	return v.compare(o), nil
+}
+
+func (v SemVer) compare(o SemVer) int {
+	if c := compareUint(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+	// A version without prerelease has higher precedence.
+	switch {
+	case len(v.Prerelease) == 0 && len(o.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(o.Prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.Prerelease) && i < len(o.Prerelease); i++ {
+		if c := comparePrerelease(v.Prerelease[i], o.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(v.Prerelease)), uint64(len(o.Prerelease)))
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePrerelease compares identifiers numerically if both are numeric,
+// numeric identifiers have lower precedence than alphanumeric ones.
+func comparePrerelease(a, b string) int {
+	an, bn := isNumeric(a), isNumeric(b)
+	switch {
+	case an && bn:
+		if c := compareUint(uint64(len(a)), uint64(len(b))); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case an:
+		return -1
+	case bn:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func toSemVer(v any) (SemVer, error) {
+	switch val := v.(type) {
+	case SemVer:
+		return val, nil
+	case string:
+		return ParseSemVer(val)
+	default:
+		return SemVer{}, trace.BadParameter("can not compare semantic version and %T", v)
+	}
+}
+
+// SemVerModule returns semantic version functions:
+//
+//	semver(s)                         - parses the version, the result
+//	                                    works with comparison operators
+//	                                    like Less and GreaterOrEqual
+//	semverMatches(version, constraint) - returns BoolPredicate,
+//	                                    e.g. semverMatches(v, "^1.2 || ~1.4")
+//	isPrerelease(version)             - returns BoolPredicate
+//
+// Versions are passed as strings or values returned by semver. See
+// ParseSemVerConstraint for the constraint syntax.
+func SemVerModule() Module {
+	return Module{
+		Functions: map[string]any{
+			"semver":        toSemVer,
+			"semverMatches": semverMatches,
+			"isPrerelease":  isPrerelease,
+		},
+		FunctionInfo: map[string]FunctionInfo{
+			"semver":        {Literals: []LiteralFunc{parseSemVerLiteral}, Pure: true},
+			"semverMatches": {Literals: []LiteralFunc{parseSemVerLiteral, parseConstraintLiteral}, Pure: true},
+			"isPrerelease":  {Literals: []LiteralFunc{parseSemVerLiteral}, Pure: true},
+		},
+	}
+}
+
+func parseSemVerLiteral(v any) (any, error) {
+	return toSemVer(v)
+}
+
+func parseConstraintLiteral(v any) (any, error) {
+	return toSemVerConstraint(v)
+}
+
+func semverMatches(version, constraint any) (BoolPredicate, error) {
+	v, err := toSemVer(version)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	c, err := toSemVerConstraint(constraint)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return func() bool {
+		return c.Matches(v)
+	}, nil
+}
+
+func isPrerelease(version any) (BoolPredicate, error) {
+	v, err := toSemVer(version)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return v.IsPrerelease, nil
+}
+
+func toSemVerConstraint(v any) (*SemVerConstraint, error) {
+	switch val := v.(type) {
+	case *SemVerConstraint:
+		return val, nil
+	case string:
+		return ParseSemVerConstraint(val)
+	default:
+		return nil, trace.BadParameter("expected version constraint to be a string, got %T", v)
+	}
+}
+
+// SemVerConstraint is a set of version ranges.
+type SemVerConstraint struct {
+	ranges []semverRange
+}
+
+// semverRange is a list of comparators that all have to match.
+type semverRange struct {
+	comparators []semverComparator
+}
+
+type semverComparator struct {
+	op      string
+	version SemVer
+}
+
+// ParseSemVerConstraint parses ranges separated by ||, each range is
+// a list of comparators separated by spaces or commas that all have to match:
+//
+//	1.2.3, =1.2.3    exactly 1.2.3
+//	>1.2.3, >=1.2.3  greater, greater or equal, < and <= are supported too
+//	1.2, 1.2.x       any 1.2 version, >=1.2.0 <1.3.0
+//	~1.2.3           patch updates, >=1.2.3 <1.3.0
+//	^1.2.3           updates that do not change the leftmost non-zero part,
+//	                 >=1.2.3 <2.0.0, ^0.2.3 is >=0.2.3 <0.3.0
+//	*                any version
+//
+// Prerelease versions only match a range if one of its comparators has
+// a prerelease of the same major.minor.patch version, so ">=1.2.0-rc.1"
+// matches 1.2.0-rc.2 but not 1.3.0-rc.1.
+func ParseSemVerConstraint(s string) (*SemVerConstraint, error) {
+	c := &SemVerConstraint{}
+	for _, rangeText := range strings.Split(s, "||") {
+		fields := strings.FieldsFunc(rangeText, func(r rune) bool {
+			return r == ' ' || r == ',' || r == '\t'
+		})
+		if len(fields) == 0 {
+			return nil, trace.BadParameter("invalid version constraint %q: empty range", s)
+		}
+		var r semverRange
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+			// Allow a space between the operator and the version, e.g. ">= 1.2".
+			if isSemVerOperator(field) && i+1 < len(fields) {
+				i++
+				field += fields[i]
+			}
+			comparators, err := parseComparator(field)
+			if err != nil {
+				return nil, trace.BadParameter("invalid version constraint %q: %v", s, err)
+			}
+			r.comparators = append(r.comparators, comparators...)
+		}
+		c.ranges = append(c.ranges, r)
+	}
+	return c, nil
+}
+
+func isSemVerOperator(s string) bool {
+	switch s {
+	case "=", ">", ">=", "<", "<=", "^", "~":
+		return true
+	default:
+		return false
+	}
+}
+
+// parseComparator expands a comparator like ^1.2 to primitive
+// comparators like >=1.2.0 <2.0.0-0.
+func parseComparator(s string) ([]semverComparator, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(s, prefix) {
+			op = prefix
+			break
+		}
+	}
+	v, parts, err := parseSemVerParts(s[len(op):])
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if v.Build != "" {
+		return nil, trace.BadParameter("build metadata is not allowed in %q", s)
+	}
+	lower := func(v SemVer) semverComparator { return semverComparator{op: ">=", version: v} }
+	// upper bounds exclude prereleases of the next version, e.g. <2.0.0-0.
+	upper := func(major, minor, patch uint64) semverComparator {
+		return semverComparator{op: "<", version: SemVer{Major: major, Minor: minor, Patch: patch, Prerelease: []string{"0"}}}
+	}
+	// next returns the upper bound of a partial version, e.g. 1.3.0 for 1.2.
+	next := func() semverComparator {
+		if parts == 1 {
+			return upper(v.Major+1, 0, 0)
+		}
+		return upper(v.Major, v.Minor+1, 0)
+	}
+
+	switch {
+	case parts == 0:
+		if op == "<" || op == ">" {
+			return nil, trace.BadParameter("%q matches no versions", s)
+		}
+		return []semverComparator{lower(SemVer{})}, nil
+	case op == "^":
+		switch {
+		case v.Major != 0 || parts == 1:
+			return []semverComparator{lower(v), upper(v.Major+1, 0, 0)}, nil
+		case v.Minor != 0 || parts == 2:
+			return []semverComparator{lower(v), upper(0, v.Minor+1, 0)}, nil
+		default:
+			return []semverComparator{lower(v), upper(0, 0, v.Patch+1)}, nil
+		}
+	case op == "~":
+		if parts == 1 {
+			return []semverComparator{lower(v), upper(v.Major+1, 0, 0)}, nil
+		}
+		return []semverComparator{lower(v), upper(v.Major, v.Minor+1, 0)}, nil
+	case parts == 3:
+		if op == "" {
+			op = "="
+		}
+		return []semverComparator{{op: op, version: v}}, nil
+	}
+
+	// Partial versions with comparison operators.
+	switch op {
+	case "", "=":
+		return []semverComparator{lower(v), next()}, nil
+	case ">=":
+		return []semverComparator{lower(v)}, nil
+	case ">":
+		n := next()
+		return []semverComparator{lower(SemVer{Major: n.version.Major, Minor: n.version.Minor})}, nil
+	case "<":
+		return []semverComparator{upper(v.Major, v.Minor, 0)}, nil
+	default: // <=
+		return []semverComparator{next()}, nil
+	}
+}
+
+// Matches returns true if the version is in any of the ranges.
+func (c *SemVerConstraint) Matches(v SemVer) bool {
+	for _, r := range c.ranges {
+		if r.matches(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r semverRange) matches(v SemVer) bool {
+	for _, c := range r.comparators {
+		if !c.matches(v) {
+			return false
+		}
+	}
+	if !v.IsPrerelease() {
+		return true
+	}
+	for _, c := range r.comparators {
+		cv := c.version
+		// Upper bounds like <2.0.0-0 do not allow prereleases.
+		if cv.IsPrerelease() && !(c.op == "<" && len(cv.Prerelease) == 1 && cv.Prerelease[0] == "0") &&
+			cv.Major == v.Major && cv.Minor == v.Minor && cv.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+func (c semverComparator) matches(v SemVer) bool {
+	cmp := v.compare(c.version)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	default: // <=
+		return cmp <= 0
+	}
+}
@@ -0,0 +1,141 @@
+package predicate
+
+import (
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSemVer(t *testing.T) {
+	t.Parallel()
+
+	v, err := ParseSemVer("v1.2.3-rc.1+build.5")
+	require.NoError(t, err)
+	require.Equal(t, SemVer{Major: 1, Minor: 2, Patch: 3, Prerelease: []string{"rc", "1"}, Build: "build.5"}, v)
+	require.Equal(t, "1.2.3-rc.1+build.5", v.String())
+
+	for _, s := range []string{"", "1.2", "1.2.3.4", "01.2.3", "1.2.3-", "1.2.3-rc..1", "1.2.3-01", "1.2.x", "a.b.c", "1.2.3+"} {
+		_, err := ParseSemVer(s)
+		require.True(t, trace.IsBadParameter(err), "%q: unexpected error %v", s, err)
+	}
+}
+
+func TestSemVerPrecedence(t *testing.T) {
+	t.Parallel()
+
+	// Ordered by precedence as in the semver specification.
+	versions := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0", "10.0.0",
+	}
+	for i := range versions {
+		for j := range versions {
+			a, err := ParseSemVer(versions[i])
+			require.NoError(t, err)
+			c, err := a.Compare(versions[j])
+			require.NoError(t, err)
+			require.Equal(t, compareUint(uint64(i), uint64(j)), c, "%v and %v", versions[i], versions[j])
+		}
+	}
+
+	c, err := Compare("1.0.0+a", mustSemVer(t, "1.0.0+b"))
+	require.NoError(t, err)
+	require.Equal(t, 0, c, "build metadata is ignored")
+}
+
+func TestSemVerConstraint(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		constraint string
+		match      []string
+		mismatch   []string
+	}{
+		{constraint: "1.2.3", match: []string{"1.2.3", "1.2.3+b"}, mismatch: []string{"1.2.4", "1.2.3-rc.1"}},
+		{constraint: "^1.2", match: []string{"1.2.0", "1.9.9"}, mismatch: []string{"1.1.9", "2.0.0", "2.0.0-rc.1"}},
+		{constraint: "^0.2.3", match: []string{"0.2.3", "0.2.9"}, mismatch: []string{"0.3.0", "0.2.2"}},
+		{constraint: "^0.0.3", match: []string{"0.0.3"}, mismatch: []string{"0.0.4"}},
+		{constraint: "~1.4", match: []string{"1.4.0", "1.4.7"}, mismatch: []string{"1.5.0", "1.3.9"}},
+		{constraint: "^1.2 || ~1.4", match: []string{"1.4.2", "1.2.0"}, mismatch: []string{"2.1.0"}},
+		{constraint: ">= 14.2.0, < 15", match: []string{"14.2.0", "14.9.0"}, mismatch: []string{"15.0.0", "14.1.9", "15.0.0-rc.1"}},
+		{constraint: ">1.2", match: []string{"1.3.0"}, mismatch: []string{"1.2.9"}},
+		{constraint: "<=1.2", match: []string{"1.2.9", "0.1.0"}, mismatch: []string{"1.3.0"}},
+		{constraint: "<1.2", match: []string{"1.1.9"}, mismatch: []string{"1.2.0"}},
+		{constraint: "1.x", match: []string{"1.0.0", "1.9.0"}, mismatch: []string{"2.0.0"}},
+		{constraint: ">=1.2.0-rc.1", match: []string{"1.2.0-rc.2", "1.3.0"}, mismatch: []string{"1.2.0-beta", "1.3.0-rc.1"}},
+		{constraint: "*", match: []string{"0.0.0", "99.0.0"}, mismatch: []string{"1.0.0-rc.1"}},
+	} {
+		t.Run(tc.constraint, func(t *testing.T) {
+			c, err := ParseSemVerConstraint(tc.constraint)
+			require.NoError(t, err)
+			for _, v := range tc.match {
+				require.True(t, c.Matches(mustSemVer(t, v)), "%v should match %v", tc.constraint, v)
+			}
+			for _, v := range tc.mismatch {
+				require.False(t, c.Matches(mustSemVer(t, v)), "%v should not match %v", tc.constraint, v)
+			}
+		})
+	}
+
+	for _, s := range []string{"", "^1.2 ||", ">=a", "1.2.3+b", "<*", "=>1.2"} {
+		_, err := ParseSemVerConstraint(s)
+		require.True(t, trace.IsBadParameter(err), "%q: unexpected error %v", s, err)
+	}
+}
+
+func TestSemVerModule(t *testing.T) {
+	t.Parallel()
+
+	p := newTestParser(t, Def{
+		Operators: Operators{
+			LT:  Less,
+			LE:  LessOrEqual,
+			GT:  Greater,
+			GE:  GreaterOrEqual,
+			EQ:  EqualValues,
+			NEQ: NotEqualValues,
+		},
+	}, map[string]any{
+		"version": "14.3.1",
+		"rc":      "15.0.0-rc.1",
+	}, SemVerModule())
+
+	for _, tc := range []struct {
+		input  string
+		expect bool
+	}{
+		{input: `client.version >= semver("14.2.0") && !isPrerelease(client.version)`, expect: true},
+		{input: `semver(client.version) < semver("14.10.0")`, expect: true},
+		{input: `semver(client.rc) > "14.99.99"`, expect: true},
+		{input: `isPrerelease(client.rc)`, expect: true},
+		{input: `semver("v14.3.1") == client.version`, expect: true},
+		{input: `semverMatches(client.version, "^14.2 || ~13.1")`, expect: true},
+		{input: `semverMatches(client.rc, ">=15")`, expect: false},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			require.Equal(t, tc.expect, parseAndEval(t, p, tc.input))
+		})
+	}
+
+	for _, tc := range []struct {
+		input         string
+		expectMessage string
+	}{
+		{input: `client.version >= semver("14.2")`, expectMessage: "1:26: invalid semantic version"},
+		{input: `semverMatches(client.version, "^14.2 ||")`, expectMessage: "1:31: invalid version constraint"},
+		{input: `semver(client.version) < 1`, expectMessage: "can not compare"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := p.Parse(tc.input)
+			require.True(t, trace.IsBadParameter(err), "unexpected error %v", err)
+			require.Contains(t, err.Error(), tc.expectMessage)
+		})
+	}
+}
+
+func mustSemVer(t *testing.T, s string) SemVer {
+	v, err := ParseSemVer(s)
+	require.NoError(t, err)
+	return v
+}
@@ -0,0 +1,221 @@
+package predicate
+
+import (
+	"reflect"
+
+	"github.com/gravitational/trace"
+)
+
+// SetsModule returns set functions over lists, available both as functions
+// and as methods, e.g. containsAny(user.roles, resource.roles) and
+// user.roles.containsAny(resource.roles):
+//
+//	intersection(a, b) - elements of a that are in b
+//	union(a, b)        - elements of a followed by elements of b not in a
+//	difference(a, b)   - elements of a that are not in b
+//	isSubset(a, b)     - returns BoolPredicate, true if all of a is in b
+//	containsAny(a, b)  - returns BoolPredicate, true if any of b is in a
+//	containsAll(a, b)  - returns BoolPredicate, true if all of b is in a
+//	len(a)             - number of elements
+//
+// Lists are []string or any other slices and arrays of comparable elements,
+// containsAny and containsAll accept a single element as b as well. Results
+// keep the order of the arguments without duplicates, they have the type
+// of the arguments, e.g. []string, if both have the same type, and []any
+// otherwise.
+func SetsModule() Module {
+	fns := map[string]any{
+		"intersection": intersection,
+		"union":        union,
+		"difference":   difference,
+		"isSubset":     isSubset,
+		"containsAny":  containsAny,
+		"containsAll":  containsAll,
+		"len":          length,
+	}
+	// Set operations build a set of each list.
+	setCost := linearSum(1, 1, 0, 1)
+	return Module{Functions: fns, Methods: copyFunctions(fns), FunctionInfo: withCosts(pureFunctions(fns), map[string]*Cost{
+		"intersection": setCost,
+		"union":        setCost,
+		"difference":   setCost,
+		"isSubset":     setCost,
+		"containsAny":  setCost,
+		"containsAll":  setCost,
+	})}
+}
+
+// setValues holds elements of a list and the set of its elements.
+type setValues struct {
+	typ      reflect.Type
+	elements []reflect.Value
+	set      map[any]struct{}
+}
+
+func (s *setValues) contains(v reflect.Value) bool {
+	_, ok := s.set[v.Interface()]
+	return ok
+}
+
+func toSet(v any) (*setValues, error) {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return nil, trace.BadParameter("expected a list, got %T", v)
+	}
+	elemType := val.Type().Elem()
+	if !elemType.Comparable() {
+		return nil, trace.BadParameter("elements of %T can not be compared", v)
+	}
+	s := &setValues{
+		typ:      reflect.SliceOf(elemType),
+		elements: make([]reflect.Value, 0, val.Len()),
+		set:      make(map[any]struct{}, val.Len()),
+	}
+	for i := 0; i < val.Len(); i++ {
+		elem := val.Index(i)
+		if !elem.CanInterface() {
+			return nil, trace.BadParameter("elements of %T can not be accessed", v)
+		}
+		if elem.Kind() == reflect.Interface && !elem.IsNil() && !elem.Elem().Type().Comparable() {
+			return nil, trace.BadParameter("element of type %v can not be compared", elem.Elem().Type())
+		}
+		if _, ok := s.set[elem.Interface()]; ok {
+			continue
+		}
+		s.set[elem.Interface()] = struct{}{}
+		s.elements = append(s.elements, elem)
+	}
+	return s, nil
+}
+
+// toSetOrElement converts lists to sets and other values to sets of one element.
+func toSetOrElement(v any) (*setValues, error) {
+	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Slice || val.Kind() == reflect.Array {
+		return toSet(v)
+	}
+	if !val.IsValid() || !val.Type().Comparable() {
+		return nil, trace.BadParameter("value of type %T can not be compared", v)
+	}
+	return &setValues{
+		typ:      reflect.SliceOf(val.Type()),
+		elements: []reflect.Value{val},
+		set:      map[any]struct{}{v: {}},
+	}, nil
+}
+
+// toSets converts both arguments to sets, and returns the type of results.
+func toSets(a, b any, toSetB func(any) (*setValues, error)) (*setValues, *setValues, reflect.Type, error) {
+	as, err := toSet(a)
+	if err != nil {
+		return nil, nil, nil, trace.Wrap(err)
+	}
+	bs, err := toSetB(b)
+	if err != nil {
+		return nil, nil, nil, trace.Wrap(err)
+	}
+	typ := as.typ
+	if as.typ != bs.typ {
+		typ = reflect.TypeOf([]any{})
+	}
+	return as, bs, typ, nil
+}
+
+func makeList(typ reflect.Type, elements []reflect.Value) any {
+	out := reflect.MakeSlice(typ, 0, len(elements))
+	for _, elem := range elements {
+		out = reflect.Append(out, elem)
+	}
+	return out.Interface()
+}
+
+func intersection(a, b any) (any, error) {
+	as, bs, typ, err := toSets(a, b, toSet)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var out []reflect.Value
+	for _, elem := range as.elements {
+		if bs.contains(elem) {
+			out = append(out, elem)
+		}
+	}
+	return makeList(typ, out), nil
+}
+
+func union(a, b any) (any, error) {
+	as, bs, typ, err := toSets(a, b, toSet)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	out := append([]reflect.Value{}, as.elements...)
+	for _, elem := range bs.elements {
+		if !as.contains(elem) {
+			out = append(out, elem)
+		}
+	}
+	return makeList(typ, out), nil
+}
+
+func difference(a, b any) (any, error) {
+	as, bs, typ, err := toSets(a, b, toSet)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var out []reflect.Value
+	for _, elem := range as.elements {
+		if !bs.contains(elem) {
+			out = append(out, elem)
+		}
+	}
+	return makeList(typ, out), nil
+}
+
+func isSubset(a, b any) (BoolPredicate, error) {
+	as, bs, _, err := toSets(a, b, toSet)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	result := containsAllOf(bs, as)
+	return func() bool {
+		return result
+	}, nil
+}
+
+func containsAny(a, b any) (BoolPredicate, error) {
+	as, bs, _, err := toSets(a, b, toSetOrElement)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	result := false
+	for _, elem := range bs.elements {
+		if as.contains(elem) {
+			result = true
+			break
+		}
+	}
+	return func() bool {
+		return result
+	}, nil
+}
+
+func containsAll(a, b any) (BoolPredicate, error) {
+	as, bs, _, err := toSets(a, b, toSetOrElement)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	result := containsAllOf(as, bs)
+	return func() bool {
+		return result
+	}, nil
+}
+
+// containsAllOf returns true if all elements of b are in a.
+func containsAllOf(a, b *setValues) bool {
+	for _, elem := range b.elements {
+		if !a.contains(elem) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package predicate
+
+import (
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetsModule(t *testing.T) {
+	t.Parallel()
+
+	p := newTestParser(t, Def{}, map[string]any{
+		"roles":   []string{"dev", "ops", "dev"},
+		"allowed": []string{"ops", "admin"},
+		"ids":     []int{1, 2, 3},
+		"mixed":   []any{1, "ops"},
+		"nested":  [][]string{{"a"}},
+		"array":   [2]string{"dev", "qa"},
+	}, SetsModule(), StringsModule()) // len is shared with the strings module
+
+	for _, tc := range []struct {
+		input  string
+		expect any
+	}{
+		{input: `intersection(roles, allowed)`, expect: []string{"ops"}},
+		{input: `roles.union(allowed)`, expect: []string{"dev", "ops", "admin"}},
+		{input: `difference(roles, allowed)`, expect: []string{"dev"}},
+		{input: `difference(allowed, roles).len()`, expect: 1},
+		{input: `len(roles)`, expect: 3},
+		{input: `isSubset(intersection(roles, allowed), allowed)`, expect: true},
+		{input: `roles.isSubset(allowed)`, expect: false},
+		{input: `containsAny(roles, allowed)`, expect: true},
+		{input: `roles.containsAny("admin")`, expect: false},
+		{input: `containsAll(roles, "dev") && !containsAll(roles, allowed)`, expect: true},
+		{input: `ids.containsAll(2)`, expect: true},
+		{input: `union(ids, mixed)`, expect: []any{1, 2, 3, "ops"}},
+		{input: `intersection(array, roles)`, expect: []string{"dev"}},
+		{input: `intersection(roles, ids)`, expect: []any{}},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			require.Equal(t, tc.expect, parseAndEval(t, p, tc.input))
+		})
+	}
+
+	for _, input := range []string{
+		`union(roles, "dev")`,
+		`isSubset("dev", roles)`,
+		`containsAny(nested, roles)`,
+		`intersection(mixed, union(mixed, nested))`,
+	} {
+		_, err := p.Parse(input)
+		require.True(t, trace.IsBadParameter(err), "%v: unexpected error %v", input, err)
+	}
+}
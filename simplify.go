@@ -0,0 +1,253 @@
+package predicate
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+
+	"github.com/gravitational/trace"
+	"github.com/vulcand/predicate/internal/astutil"
+)
+
+// Simplify returns the expression rewritten to an equivalent simpler one and
+// printed with single spaces around binary operators and with parentheses
+// only where operator precedence requires them, e.g.
+//
+//	true && !(!user.admin) && (user.admin || "a" == "b")
+//
+// is simplified to
+//
+//	user.admin
+//
+// The rewrites are:
+//
+//   - && and || with boolean constants are folded, true && x is x,
+//     false && x is false, true || x is true and false || x is x
+//   - double negation is removed, !!x is x
+//   - repeated operands of && and || chains are removed, x && y && x is x && y
+//   - operators with literal operands are called, e.g. "a" == "b" is false
+//   - functions and methods marked as pure in Def.FunctionInfo are called
+//     if all of their arguments are literals, e.g. lower("ADMIN") is "admin"
+//
+// Identifiers true and false are boolean constants. Results that can not be
+// written as literals, e.g. lists, are left as calls. Rules that simplify to
+// a constant are printed as true or false, so GetIdentifier has to resolve
+// them, as for any other rule using them.
+func (d Def) Simplify(in string) (string, error) {
+	expr, err := parser.ParseExpr(in)
+	if err != nil {
+		return "", err
+	}
+	out, err := d.SimplifyExpr(expr)
+	if err != nil {
+		return "", withPosition(in, err)
+	}
+	return astutil.ExprString(out), nil
+}
+
+// SimplifyExpr simplifies the parsed expression as described in Simplify,
+// expr is not modified.
+func (d Def) SimplifyExpr(expr ast.Expr) (ast.Expr, error) {
+	s := &simplifier{p: &predicateParser{d: d}}
+	out, err := s.simplify(expr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return out, nil
+}
+
+type simplifier struct {
+	p *predicateParser
+}
+
+func (s *simplifier) simplify(expr ast.Expr) (ast.Expr, error) {
+	switch n := expr.(type) {
+	case *ast.ParenExpr:
+		return s.simplify(n.X)
+
+	case *ast.BasicLit, *ast.Ident, *ast.SelectorExpr:
+		return n, nil
+
+	case *ast.IndexExpr:
+		x, err := s.simplify(n.X)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		index, err := s.simplify(n.Index)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return &ast.IndexExpr{X: x, Lbrack: n.Lbrack, Index: index, Rbrack: n.Rbrack}, nil
+
+	case *ast.UnaryExpr:
+		return s.simplifyUnary(n)
+
+	case *ast.BinaryExpr:
+		if n.Op == token.LAND || n.Op == token.LOR {
+			return s.simplifyLogical(n)
+		}
+		return s.simplifyBinary(n)
+
+	case *ast.CallExpr:
+		return s.simplifyCall(n)
+
+	default:
+		return nil, trace.BadParameter("%T is not supported", expr)
+	}
+}
+
+func (s *simplifier) simplifyUnary(n *ast.UnaryExpr) (ast.Expr, error) {
+	x, err := s.simplify(n.X)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if n.Op == token.NOT {
+		if b, ok := boolConst(x); ok {
+			return boolIdent(!b), nil
+		}
+		if inner, ok := x.(*ast.UnaryExpr); ok && inner.Op == token.NOT {
+			return inner.X, nil
+		}
+	}
+	return &ast.UnaryExpr{OpPos: n.OpPos, Op: n.Op, X: x}, nil
+}
+
+// simplifyLogical folds constants and removes repeated operands
+// of a chain of && or || operators.
+func (s *simplifier) simplifyLogical(n *ast.BinaryExpr) (ast.Expr, error) {
+	// x && y is x when y is true, and false when y is false.
+	identity := n.Op == token.LAND
+	var operands []ast.Expr
+	seen := make(map[string]struct{})
+	for _, operand := range astutil.LogicalOperands(n.Op, n) {
+		x, err := s.simplify(operand)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, x := range astutil.LogicalOperands(n.Op, x) {
+			if b, ok := boolConst(x); ok {
+				if b != identity {
+					return boolIdent(b), nil
+				}
+				continue
+			}
+			key := astutil.ExprString(x)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			operands = append(operands, x)
+		}
+	}
+	if len(operands) == 0 {
+		return boolIdent(identity), nil
+	}
+	out := operands[0]
+	for _, y := range operands[1:] {
+		out = &ast.BinaryExpr{X: out, OpPos: n.OpPos, Op: n.Op, Y: y}
+	}
+	return out, nil
+}
+
+func (s *simplifier) simplifyBinary(n *ast.BinaryExpr) (ast.Expr, error) {
+	x, err := s.simplify(n.X)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	y, err := s.simplify(n.Y)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	out := &ast.BinaryExpr{X: x, OpPos: n.OpPos, Op: n.Op, Y: y}
+	xv, xok := literalValue(x)
+	yv, yok := literalValue(y)
+	if !xok || !yok {
+		return out, nil
+	}
+	if _, err := s.p.getJoinFunction(n.Op); err != nil {
+		// Operators that are not defined are reported by Parse.
+		return out, nil
+	}
+	val, err := s.p.joinPredicates(n.Op, xv, yv)
+	if err != nil {
+		return nil, &nodeError{node: n, err: err}
+	}
+	if lit, ok := valueToExpr(val, n.Pos()); ok {
+		return lit, nil
+	}
+	return out, nil
+}
+
+func (s *simplifier) simplifyCall(n *ast.CallExpr) (ast.Expr, error) {
+	name, fn, args, err := s.p.getFunctionAndArgs(n)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	simplified := make([]ast.Expr, len(args))
+	values := make([]any, len(args))
+	literal := true
+	for i, arg := range args {
+		simplified[i], err = s.simplify(arg)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		var ok bool
+		values[i], ok = literalValue(simplified[i])
+		literal = literal && ok
+	}
+
+	out := &ast.CallExpr{Fun: n.Fun, Lparen: n.Lparen, Args: simplified, Rparen: n.Rparen}
+	if len(args) != len(n.Args) {
+		// Methods get the receiver as the first argument.
+		sel := n.Fun.(*ast.SelectorExpr)
+		out.Fun = &ast.SelectorExpr{X: simplified[0], Sel: sel.Sel}
+		out.Args = simplified[1:]
+	}
+	if !literal || !s.p.d.FunctionInfo[name].Pure {
+		return out, nil
+	}
+
+	prepared, err := s.p.prepareLiterals(name, fn, simplified)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for i, val := range prepared {
+		values[i] = val
+	}
+	val, err := callFunction(fn, values)
+	if err != nil {
+		return nil, &nodeError{node: n, err: err}
+	}
+	if lit, ok := valueToExpr(val, n.Pos()); ok {
+		return lit, nil
+	}
+	return out, nil
+}
+
+// literalValue returns the value of literals and boolean constants.
+func literalValue(expr ast.Expr) (any, bool) {
+	switch n := astutil.Unparen(expr).(type) {
+	case *ast.BasicLit:
+		val, err := literalToValue(n)
+		return val, err == nil
+	case *ast.Ident:
+		b, ok := boolConst(n)
+		return b, ok
+	default:
+		return nil, false
+	}
+}
+
+// boolConst returns the value of identifiers true and false.
+func boolConst(expr ast.Expr) (bool, bool) {
+	id, ok := astutil.Unparen(expr).(*ast.Ident)
+	if !ok || (id.Name != "true" && id.Name != "false") {
+		return false, false
+	}
+	return id.Name == "true", true
+}
+
+func boolIdent(b bool) *ast.Ident {
+	return ast.NewIdent(strconv.FormatBool(b))
+}
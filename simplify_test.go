@@ -0,0 +1,79 @@
+package predicate
+
+import (
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimplify(t *testing.T) {
+	t.Parallel()
+
+	regex, err := RegexModule(RegexOptions{})
+	require.NoError(t, err)
+	d, err := Def{
+		Operators: Operators{
+			AND: And,
+			OR:  Or,
+			NOT: Not,
+			EQ:  EqualValues,
+			NEQ: NotEqualValues,
+			LT:  Less,
+		},
+		Functions: map[string]any{
+			"contains": Contains,
+			"now": func() string {
+				return "now"
+			},
+		},
+	}.Merge(StringsModule(), regex)
+	require.NoError(t, err)
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: `true && x`, want: `x`},
+		{in: `x && true`, want: `x`},
+		{in: `false && x`, want: `false`},
+		{in: `x || true`, want: `true`},
+		{in: `false || x`, want: `x`},
+		{in: `true && true`, want: `true`},
+		{in: `false || false`, want: `false`},
+		{in: `!(!x)`, want: `x`},
+		{in: `!!!x`, want: `!x`},
+		{in: `!true`, want: `false`},
+		{in: `x && y && x`, want: `x && y`},
+		{in: `(x || y) && (y || x) && (x || y)`, want: `(x || y) && (y || x)`},
+		{in: `a && (b && (a && c))`, want: `a && b && c`},
+		{in: `a || b && a`, want: `a || b && a`},
+		{in: `"a" == "a"`, want: `true`},
+		{in: `"a" == "b" || x`, want: `x`},
+		{in: `1 < 2 && x`, want: `x`},
+		{in: `user.name == lower("ALICE")`, want: `user.name == "alice"`},
+		{in: `user.name.matches(lower("^A.*"))`, want: `user.name.matches("^a.*")`},
+		{in: `"ADMIN".lower() == "admin"`, want: `true`},
+		{in: `matches("abc", "^a") && x`, want: `x`},
+		{in: `len("abc") == 3`, want: `true`},
+		{in: `contains(split("a,b", ","), x)`, want: `contains(split("a,b", ","), x)`},
+		{in: `contains(user.logins, "root")`, want: `contains(user.logins, "root")`},
+		{in: `now() == "now"`, want: `now() == "now"`},
+		{in: `labels[lower("ENV")] == "prod"`, want: `labels["env"] == "prod"`},
+		{in: `true && !(!user.admin) && (user.admin || "a" == "b")`, want: `user.admin`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			out, err := d.Simplify(tt.in)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, out)
+		})
+	}
+
+	_, err = d.Simplify(`x && matches("a", "(")`)
+	require.True(t, trace.IsBadParameter(err), "unexpected error %v", err)
+	require.Contains(t, err.Error(), "1:19")
+
+	_, err = d.Simplify(`missing("a")`)
+	require.True(t, trace.IsBadParameter(err), "unexpected error %v", err)
+}
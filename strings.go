@@ -0,0 +1,89 @@
+package predicate
+
+import (
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gravitational/trace"
+)
+
+// StringsModule returns string functions that are available both as
+// functions and as methods, e.g. hasPrefix(name, "svc-") and
+// name.hasPrefix("svc-"):
+//
+//	lower(s), upper(s)          - Unicode case mapping without special
+//	                              casing rules of any particular language
+//	hasPrefix(s, prefix)        - returns BoolPredicate
+//	hasSuffix(s, suffix)        - returns BoolPredicate
+//	trim(s), trim(s, cutset)    - removes leading and trailing Unicode
+//	                              white space or code points in cutset
+//	split(s, sep)               - returns []string
+//	join(list, sep)             - joins []string with sep
+//	replace(s, old, new)        - replaces all occurrences of old
+//	len(v)                      - length of a string in code points (runes),
+//	                              or number of elements of a list or a map
+//
+// Strings are compared byte by byte as UTF-8 without Unicode normalization,
+// so precomposed and decomposed forms of the same character are different.
+func StringsModule() Module {
+	fns := map[string]any{
+		"lower":     strings.ToLower,
+		"upper":     strings.ToUpper,
+		"hasPrefix": hasPrefix,
+		"hasSuffix": hasSuffix,
+		"trim":      trim,
+		"split":     strings.Split,
+		"join":      strings.Join,
+		"replace":   strings.ReplaceAll,
+		"len":       length,
+	}
+	return Module{Functions: fns, Methods: copyFunctions(fns), FunctionInfo: withCosts(pureFunctions(fns), map[string]*Cost{
+		"lower":     Linear(1, 1, 0),
+		"upper":     Linear(1, 1, 0),
+		"hasPrefix": Linear(1, 1, 1),
+		"hasSuffix": Linear(1, 1, 1),
+		"trim":      Linear(1, 1, 0),
+		"split":     Linear(1, 1, 0),
+		"join":      Linear(1, 1, 0),
+		"replace":   Linear(1, 1, 0),
+	})}
+}
+
+func hasPrefix(s, prefix string) BoolPredicate {
+	return func() bool {
+		return strings.HasPrefix(s, prefix)
+	}
+}
+
+func hasSuffix(s, suffix string) BoolPredicate {
+	return func() bool {
+		return strings.HasSuffix(s, suffix)
+	}
+}
+
+func trim(s string, cutset ...string) (string, error) {
+	switch len(cutset) {
+	case 0:
+		return strings.TrimSpace(s), nil
+	case 1:
+		return strings.Trim(s, cutset[0]), nil
+	default:
+		return "", trace.BadParameter("trim expects at most one cutset, got %v", len(cutset))
+	}
+}
+
+// length returns the number of code points in a string, or the number of
+// elements in a slice, an array or a map.
+func length(v any) (int, error) {
+	if s, ok := v.(string); ok {
+		return utf8.RuneCountInString(s), nil
+	}
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return val.Len(), nil
+	default:
+		return 0, trace.BadParameter("len is not supported for %T", v)
+	}
+}
@@ -0,0 +1,51 @@
+package predicate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStringsModule(t *testing.T) {
+	t.Parallel()
+
+	p := newTestParser(t, Def{Functions: map[string]any{"equals": Equals}}, map[string]any{
+		"name":   "svc-Ünïcode",
+		"logins": []string{"root", "admin"},
+	}, StringsModule())
+
+	for _, tc := range []struct {
+		desc   string
+		input  string
+		expect any
+	}{
+		{desc: "lower", input: `lower(name)`, expect: "svc-ünïcode"},
+		{desc: "upper method", input: `name.upper()`, expect: "SVC-ÜNÏCODE"},
+		{desc: "has prefix", input: `hasPrefix(name, "svc-")`, expect: true},
+		{desc: "has prefix method", input: `name.hasPrefix("db-")`, expect: false},
+		{desc: "has suffix method", input: `name.lower().hasSuffix("code")`, expect: true},
+		{desc: "combined with operators", input: `name.hasPrefix("svc-") && !hasSuffix(name, "x")`, expect: true},
+		{desc: "trim space", input: `trim("  a b\t")`, expect: "a b"},
+		{desc: "trim cutset", input: `"--a--".trim("-")`, expect: "a"},
+		{desc: "split", input: `split("a,b", ",")`, expect: []string{"a", "b"}},
+		{desc: "join method", input: `logins.join(",")`, expect: "root,admin"},
+		{desc: "replace", input: `replace(name, "svc-", "")`, expect: "Ünïcode"},
+		{desc: "len counts runes", input: `len(name)`, expect: 11},
+		{desc: "len of list", input: `logins.len()`, expect: 2},
+		{desc: "result as argument", input: `equals(lower("A"), "a")`, expect: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			require.Equal(t, tc.expect, parseAndEval(t, p, tc.input))
+		})
+	}
+
+	for _, input := range []string{
+		`len(1)`,
+		`trim("a", "b", "c")`,
+		`lower(1)`,
+		`hasPrefix("a")`,
+	} {
+		_, err := p.Parse(input)
+		require.Error(t, err, input)
+	}
+}
@@ -0,0 +1,136 @@
+package predicate
+
+import (
+	"go/ast"
+	"go/token"
+
+	"github.com/gravitational/trace"
+)
+
+// unknown is the value of identifiers that are not found, and of operators
+// and functions with unknown arguments, when Def.ThreeValued is set.
+type unknown struct{}
+
+func isUnknown(v any) bool {
+	_, ok := v.(unknown)
+	return ok
+}
+
+// toTri converts booleans, boolean predicates and unknown values to TriPredicate.
+func toTri(v any) (TriPredicate, bool) {
+	switch t := v.(type) {
+	case unknown:
+		return func() Tri { return TriUnknown }, true
+	case Tri:
+		return func() Tri { return t }, true
+	case TriPredicate:
+		return t, true
+	case func() Tri:
+		return t, true
+	case bool:
+		return func() Tri { return TriOf(t) }, true
+	case BoolPredicate:
+		return func() Tri { return TriOf(t()) }, true
+	case func() bool:
+		return func() Tri { return TriOf(t()) }, true
+	default:
+		return nil, false
+	}
+}
+
+// isTri returns true for values that need three-valued logic.
+func isTri(v any) bool {
+	switch v.(type) {
+	case unknown, Tri, TriPredicate, func() Tri:
+		return true
+	default:
+		return false
+	}
+}
+
+// unknownIdentifier returns the value of the identifier that failed with err,
+// true and false are booleans unless GetIdentifier defines them.
+func (p *predicateParser) unknownIdentifier(n ast.Expr, err error) (any, error) {
+	if !p.d.ThreeValued || !trace.IsNotFound(err) {
+		return nil, trace.Wrap(err)
+	}
+	if id, ok := n.(*ast.Ident); ok && (id.Name == "true" || id.Name == "false") {
+		return id.Name == "true", nil
+	}
+	return unknown{}, nil
+}
+
+// joinTri joins operands using Kleene logic if any of them is unknown or
+// a three-valued predicate, it returns false if the operator should be
+// called as usual.
+func (p *predicateParser) joinTri(op token.Token, x, y any) (any, bool, error) {
+	if !p.d.ThreeValued || (!isTri(x) && !isTri(y)) {
+		return nil, false, nil
+	}
+	switch op {
+	case token.LAND, token.LOR:
+		a, ok := toTri(x)
+		if !ok {
+			return nil, true, trace.BadParameter("%v expects boolean operands, got %T", op, x)
+		}
+		b, ok := toTri(y)
+		if !ok {
+			return nil, true, trace.BadParameter("%v expects boolean operands, got %T", op, y)
+		}
+		if op == token.LAND {
+			return AndTri(a, b), true, nil
+		}
+		return OrTri(a, b), true, nil
+	default:
+		if isUnknown(x) || isUnknown(y) {
+			return unknown{}, true, nil
+		}
+		return nil, false, nil
+	}
+}
+
+// parseUnaryTri evaluates the operand first, as negating unknown values
+// and three-valued predicates does not need Operators.NOT.
+func (p *predicateParser) parseUnaryTri(expr *ast.UnaryExpr) (any, error) {
+	node, err := p.parse(expr.X)
+	if err != nil {
+		return nil, err
+	}
+
+	if val, ok := p.notTri(expr.Op, node); ok {
+		return val, nil
+	}
+
+	joinFn, err := p.getJoinFunction(expr.Op)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := callFunction(joinFn, []any{node})
+	return val, trace.Wrap(err)
+}
+
+// notTri negates unknown values and three-valued predicates.
+func (p *predicateParser) notTri(op token.Token, x any) (any, bool) {
+	if !p.d.ThreeValued || !isTri(x) {
+		return nil, false
+	}
+	if op != token.NOT {
+		if isUnknown(x) {
+			return unknown{}, true
+		}
+		return nil, false
+	}
+	t, _ := toTri(x)
+	return NotTri(t), true
+}
+
+// anyUnknown returns true if any of the values is unknown.
+func anyUnknown(values []any) bool {
+	for _, v := range values {
+		if isUnknown(v) {
+			return true
+		}
+	}
+	return false
+}
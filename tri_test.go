@@ -0,0 +1,140 @@
+package predicate
+
+import (
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriOperators(t *testing.T) {
+	t.Parallel()
+
+	values := []Tri{TriFalse, TriTrue, TriUnknown}
+	and := [][]Tri{
+		{TriFalse, TriFalse, TriFalse},
+		{TriFalse, TriTrue, TriUnknown},
+		{TriFalse, TriUnknown, TriUnknown},
+	}
+	or := [][]Tri{
+		{TriFalse, TriTrue, TriUnknown},
+		{TriTrue, TriTrue, TriTrue},
+		{TriUnknown, TriTrue, TriUnknown},
+	}
+	constant := func(v Tri) TriPredicate {
+		return func() Tri { return v }
+	}
+	for i, a := range values {
+		for j, b := range values {
+			require.Equal(t, and[i][j], AndTri(constant(a), constant(b))(), "%v && %v", a, b)
+			require.Equal(t, or[i][j], OrTri(constant(a), constant(b))(), "%v || %v", a, b)
+		}
+	}
+	require.Equal(t, TriTrue, NotTri(constant(TriFalse))())
+	require.Equal(t, TriFalse, NotTri(constant(TriTrue))())
+	require.Equal(t, TriUnknown, NotTri(constant(TriUnknown))())
+	require.Equal(t, "unknown", TriUnknown.String())
+}
+
+func TestThreeValued(t *testing.T) {
+	t.Parallel()
+
+	doc := map[string]any{
+		"user": map[string]any{
+			"name":   "alice",
+			"roles":  []any{"dev"},
+			"traits": map[string]any{"team": "sre"},
+		},
+	}
+	d, err := Def{
+		Operators: Operators{
+			AND: And,
+			OR:  Or,
+			NOT: Not,
+			EQ:  EqualValues,
+			NEQ: NotEqualValues,
+		},
+		Functions: map[string]any{
+			"contains": Contains,
+		},
+		GetIdentifier: GetJSONIdentifier(doc),
+		GetProperty:   GetJSONProperty,
+		ThreeValued:   true,
+	}.Merge(StringsModule())
+	require.NoError(t, err)
+
+	tests := []struct {
+		in   string
+		want Tri
+	}{
+		{in: `user.name == "alice"`, want: TriTrue},
+		{in: `user.name == "bob"`, want: TriFalse},
+		{in: `user.email == "alice@example.com"`, want: TriUnknown},
+		{in: `!(user.email == "alice@example.com")`, want: TriUnknown},
+		{in: `user.email == "alice@example.com" && user.name == "bob"`, want: TriFalse},
+		{in: `user.email == "alice@example.com" && user.name == "alice"`, want: TriUnknown},
+		{in: `user.email == "alice@example.com" || user.name == "alice"`, want: TriTrue},
+		{in: `user.email == "alice@example.com" || user.name == "bob"`, want: TriUnknown},
+		{in: `contains(user.roles, "dev")`, want: TriTrue},
+		{in: `contains(user.groups, "dev")`, want: TriUnknown},
+		{in: `lower(user.email) == "a"`, want: TriUnknown},
+		{in: `user.email.lower() == "a"`, want: TriUnknown},
+		{in: `user.traits["team"] == "sre"`, want: TriTrue},
+		{in: `user.traits["env"] == "prod"`, want: TriUnknown},
+		{in: `user.traits[user.key] == "prod"`, want: TriUnknown},
+		{in: `true && user.email == "a"`, want: TriUnknown},
+		{in: `false && user.email == "a"`, want: TriFalse},
+		{in: `user.missing`, want: TriUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			p, err := NewParser(d)
+			require.NoError(t, err)
+			result, err := p.Parse(tt.in)
+			require.NoError(t, err)
+			fn, ok := result.(TriPredicate)
+			require.True(t, ok, "expected TriPredicate, got %T", result)
+			require.Equal(t, tt.want, fn())
+		})
+	}
+}
+
+func TestThreeValuedErrors(t *testing.T) {
+	t.Parallel()
+
+	d := Def{
+		Operators: Operators{AND: And, EQ: EqualValues},
+		GetIdentifier: func(selector []string) (any, error) {
+			if selector[0] == "denied" {
+				return nil, trace.AccessDenied("access denied")
+			}
+			if selector[0] == "name" {
+				return "alice", nil
+			}
+			return nil, trace.NotFound("not found")
+		},
+		ThreeValued: true,
+	}
+	p, err := NewParser(d)
+	require.NoError(t, err)
+
+	// Errors other than not found fail parsing.
+	_, err = p.Parse(`denied == "a"`)
+	require.True(t, trace.IsAccessDenied(err), "unexpected error %v", err)
+
+	// Logical operators expect boolean operands.
+	_, err = p.Parse(`missing == "a" && name`)
+	require.True(t, trace.IsBadParameter(err), "unexpected error %v", err)
+
+	// Without the mode, missing identifiers fail parsing.
+	d.ThreeValued = false
+	p, err = NewParser(d)
+	require.NoError(t, err)
+	_, err = p.Parse(`missing == "a"`)
+	require.True(t, trace.IsNotFound(err), "unexpected error %v", err)
+
+	// Unsupported operators are reported before their operands are evaluated.
+	_, err = p.Parse(`!missing`)
+	require.True(t, trace.IsBadParameter(err), "unexpected error %v", err)
+	require.Contains(t, err.Error(), "! is not supported")
+}
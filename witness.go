@@ -0,0 +1,170 @@
+package predicate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/trace"
+	"github.com/vulcand/predicate/internal/astutil"
+)
+
+// Example is a test case for a rule.
+type Example struct {
+	// Model holds values of identifiers, e.g. user.traits.team = ["sre"].
+	Model Model
+	// Allowed is true if the rule is true for the values of the model.
+	Allowed bool
+}
+
+// Witness returns values of identifiers for which the rule is true, e.g.
+// {"user.traits.team": ["sre"]} for contains(user.traits.team, "sre").
+// Status is Unsatisfiable if the rule is never true, and SatUnknown if the
+// solver could not find values, see Satisfiable for the atoms it understands.
+// The model has values for all identifiers the solver understands, the ones
+// that do not affect the result have zero values: false, "", 0 or empty lists.
+func (d Def) Witness(in string, opts SatOptions) (SatResult, error) {
+	expr, err := parser.ParseExpr(in)
+	if err != nil {
+		return SatResult{}, err
+	}
+	return d.witness(expr, opts)
+}
+
+// Counterexample returns values of identifiers for which the rule is false,
+// as described in Witness. Status is Unsatisfiable if the rule is always true.
+func (d Def) Counterexample(in string, opts SatOptions) (SatResult, error) {
+	expr, err := parser.ParseExpr(in)
+	if err != nil {
+		return SatResult{}, err
+	}
+	return d.witness(&ast.UnaryExpr{Op: token.NOT, X: expr}, opts)
+}
+
+func (d Def) witness(expr ast.Expr, opts SatOptions) (SatResult, error) {
+	result, err := d.SatisfiableExpr(expr, opts)
+	if err != nil {
+		return SatResult{}, trace.Wrap(err)
+	}
+	if result.Status == Satisfiable {
+		if err := d.completeModel(expr, result.Model, opts); err != nil {
+			return SatResult{}, trace.Wrap(err)
+		}
+	}
+	return result, nil
+}
+
+// Examples returns test cases for the rule derived from its structure: an
+// allowed example for every way the rule can be true, i.e. every clause of
+// its DNF, and a denied example for every clause of the DNF of its negation.
+// Clauses the solver can not decide are skipped, repeated examples are removed.
+// Rules with normal forms bigger than opts allow fail with trace.LimitExceeded.
+func (d Def) Examples(in string, opts SatOptions) ([]Example, error) {
+	expr, err := parser.ParseExpr(in)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var out []Example
+	seen := make(map[string]struct{})
+	for _, allowed := range []bool{true, false} {
+		target := expr
+		if !allowed {
+			target = &ast.UnaryExpr{Op: token.NOT, X: expr}
+		}
+		dnf, err := d.DNF(target, opts.NormalFormOptions)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		for _, clause := range dnf.Clauses {
+			status, model := solveClause(clause, opts)
+			if status != Satisfiable {
+				continue
+			}
+			if err := d.completeModel(expr, model, opts); err != nil {
+				return nil, trace.Wrap(err)
+			}
+			key := modelKey(model)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			out = append(out, Example{Model: model, Allowed: allowed})
+		}
+	}
+	return out, nil
+}
+
+// completeModel adds zero values of identifiers of the expression missing
+// from the model. The model satisfies a DNF clause, so values of identifiers
+// outside of the clause do not change the result.
+func (d Def) completeModel(expr ast.Expr, model Model, opts SatOptions) error {
+	nnf, err := d.NNF(expr)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	s := newClauseSolver(opts)
+	for _, lit := range logicalLiterals(nnf.Expr) {
+		s.add(lit)
+	}
+	for _, path := range s.order {
+		if _, ok := model[path]; ok {
+			continue
+		}
+		v := s.vars[path]
+		if v.mixed {
+			continue
+		}
+		switch v.kind {
+		case varBool:
+			model[path] = false
+		case varList:
+			model[path] = []string{}
+		case varScalar:
+			model[path] = zeroValue(v.comparisons[0].value)
+		}
+	}
+	return nil
+}
+
+// logicalLiterals returns operands of && and || in the expression in NNF.
+func logicalLiterals(expr ast.Expr) []ast.Expr {
+	bin, ok := astutil.Unparen(expr).(*ast.BinaryExpr)
+	if !ok || (bin.Op != token.LAND && bin.Op != token.LOR) {
+		return []ast.Expr{expr}
+	}
+	return append(logicalLiterals(bin.X), logicalLiterals(bin.Y)...)
+}
+
+// zeroValue returns the zero value of the literal's type.
+func zeroValue(v any) any {
+	switch v.(type) {
+	case string:
+		return ""
+	case bool:
+		return false
+	case float64:
+		return 0.0
+	default:
+		return 0
+	}
+}
+
+// modelKey returns the text of the model with sorted keys.
+func modelKey(m Model) string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&sb, "%v=%#v;", key, m[key])
+	}
+	return sb.String()
+}
@@ -0,0 +1,91 @@
+package predicate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWitness(t *testing.T) {
+	t.Parallel()
+
+	d := Def{}
+	result, err := d.Witness(`contains(user.traits.team, "sre") && user.level > 2`, SatOptions{})
+	require.NoError(t, err)
+	require.Equal(t, Satisfiable, result.Status)
+	require.Equal(t, Model{
+		"user.traits.team": []string{"sre"},
+		"user.level":       3,
+	}, result.Model)
+
+	val, err := result.Model.GetIdentifier([]string{"user", "traits", "team"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"sre"}, val)
+
+	// Identifiers that do not matter get zero values.
+	result, err = d.Witness(`env == "prod" || admin`, SatOptions{})
+	require.NoError(t, err)
+	require.Equal(t, Model{"env": "prod", "admin": false}, result.Model)
+
+	result, err = d.Witness(`env == "prod" && env == "dev"`, SatOptions{})
+	require.NoError(t, err)
+	require.Equal(t, Unsatisfiable, result.Status)
+}
+
+func TestCounterexample(t *testing.T) {
+	t.Parallel()
+
+	d := Def{}
+	result, err := d.Counterexample(`contains(user.traits.team, "sre") && user.level > 2`, SatOptions{})
+	require.NoError(t, err)
+	require.Equal(t, Satisfiable, result.Status)
+	require.Equal(t, Model{
+		"user.traits.team": []string{},
+		"user.level":       0,
+	}, result.Model)
+
+	result, err = d.Counterexample(`admin || !admin`, SatOptions{})
+	require.NoError(t, err)
+	require.Equal(t, Unsatisfiable, result.Status)
+}
+
+func TestExamples(t *testing.T) {
+	t.Parallel()
+
+	d := Def{
+		Operators: Operators{
+			AND: And,
+			OR:  Or,
+			NOT: Not,
+			EQ:  EqualValues,
+			NEQ: NotEqualValues,
+			GT:  Greater,
+			LE:  LessOrEqual,
+		},
+		Functions: map[string]any{
+			"contains": Contains,
+		},
+	}
+	rule := `env == "prod" && (contains(roles, "admin") || level > 3)`
+	examples, err := d.Examples(rule, SatOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []Example{
+		{Model: Model{"env": "prod", "roles": []string{"admin"}, "level": 0}, Allowed: true},
+		{Model: Model{"env": "prod", "roles": []string{}, "level": 4}, Allowed: true},
+		{Model: Model{"env": "proda", "roles": []string{}, "level": 0}, Allowed: false},
+		{Model: Model{"env": "", "roles": []string{}, "level": 3}, Allowed: false},
+	}, examples)
+
+	// Examples are test cases for the rule.
+	for _, example := range examples {
+		d := d
+		d.GetIdentifier = example.Model.GetIdentifier
+		p, err := NewParser(d)
+		require.NoError(t, err)
+		result, err := p.Parse(rule)
+		require.NoError(t, err)
+		allowed, ok := toBool(result)
+		require.True(t, ok)
+		require.Equal(t, example.Allowed, allowed, "model %v", example.Model)
+	}
+}